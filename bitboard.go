@@ -9,20 +9,37 @@ import (
 // 64 board positions begin with A1 as the most significant bit and H8 as the least.
 type bitboard uint64
 
+// Bitboard is a set of squares such as the occupied squares of a
+// board.
+type Bitboard uint64
+
+// NewBitboard returns a bitboard containing the given squares.
+func NewBitboard(sqs ...Square) Bitboard {
+	var bb bitboard
+	for _, sq := range sqs {
+		bb |= bbForSquare(sq)
+	}
+	return Bitboard(bb)
+}
+
+// Occupied returns true if the square is in the bitboard.
+func (b Bitboard) Occupied(sq Square) bool {
+	return bitboard(b).Occupied(sq)
+}
+
+// Without returns the bitboard with the square removed.
+func (b Bitboard) Without(sq Square) Bitboard {
+	return b &^ Bitboard(bbForSquare(sq))
+}
+
 func newBitboard(m map[Square]bool) bitboard {
-	s := ""
-	for sq := 0; sq < numOfSquaresInBoard; sq++ {
-		if m[Square(sq)] {
-			s += "1"
-		} else {
-			s += "0"
+	var bb bitboard
+	for sq, ok := range m {
+		if ok && sq >= 0 && int(sq) < numOfSquaresInBoard {
+			bb |= bbForSquare(sq)
 		}
 	}
-	bb, err := strconv.ParseUint(s, 2, 64)
-	if err != nil {
-		panic(err)
-	}
-	return bitboard(bb)
+	return bb
 }
 
 func (b bitboard) Mapping() map[Square]bool {
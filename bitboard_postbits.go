@@ -13,3 +13,8 @@ func (b bitboard) Reverse() bitboard {
 func (b bitboard) Occupied(sq Square) bool {
 	return (bits.RotateLeft64(uint64(b), int(sq)+1) & 1) == 1
 }
+
+// Count returns the number of occupied squares.
+func (b bitboard) Count() int {
+	return bits.OnesCount64(uint64(b))
+}
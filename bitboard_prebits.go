@@ -39,4 +39,13 @@ func (b bitboard) Occupied(sq Square) bool {
 	return (uint64(b) >> uint64(63-sq) & 1) == 1
 }
 
+// Count returns the number of occupied squares.
+func (b bitboard) Count() int {
+	count := 0
+	for ; b != 0; b &= b - 1 {
+		count++
+	}
+	return count
+}
+
 //
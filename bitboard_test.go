@@ -43,6 +43,15 @@ func TestBitboardOccupied(t *testing.T) {
 	}
 }
 
+func TestBitboardCount(t *testing.T) {
+	if bbRank1.Count() != 8 {
+		t.Fatalf("expected count %d but got %d", 8, bbRank1.Count())
+	}
+	if (bbRank1 | bbFileA).Count() != 15 {
+		t.Fatalf("expected count %d but got %d", 15, (bbRank1 | bbFileA).Count())
+	}
+}
+
 func BenchmarkBitboardReverse(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		u := uint64(9223372036854775807)
@@ -63,6 +63,11 @@ func (b *Board) Rotate() *Board {
 	return b.Flip(UpDown).Transpose()
 }
 
+// Rotate180 rotates the board 180 degrees.
+func (b *Board) Rotate180() *Board {
+	return b.Flip(UpDown).Flip(LeftRight)
+}
+
 // FlipDirection is the direction for the Board.Flip method
 type FlipDirection int
 
@@ -151,6 +156,11 @@ func (b *Board) String() string {
 	return fen
 }
 
+// Occupied returns the bitboard of the board's occupied squares.
+func (b *Board) Occupied() Bitboard {
+	return Bitboard(^b.emptySqs)
+}
+
 // Piece returns the piece for the given square.
 func (b *Board) Piece(sq Square) Piece {
 	for _, p := range allPieces {
@@ -216,6 +226,10 @@ func (b *Board) UnmarshalBinary(data []byte) error {
 }
 
 func (b *Board) update(m *Move) {
+	if m.HasTag(KingSideCastle) || m.HasTag(QueenSideCastle) {
+		b.castle(m)
+		return
+	}
 	p1 := b.Piece(m.s1)
 	s1BB := bbForSquare(m.s1)
 	s2BB := bbForSquare(m.s2)
@@ -249,19 +263,96 @@ func (b *Board) update(m *Move) {
 			b.bbWhitePawn = ^(bbForSquare(m.s2) >> 8) & b.bbWhitePawn
 		}
 	}
-	// move rook for castle
-	if p1.Color() == White && m.HasTag(KingSideCastle) {
-		b.bbWhiteRook = (b.bbWhiteRook & ^bbForSquare(H1) | bbForSquare(F1))
-	} else if p1.Color() == White && m.HasTag(QueenSideCastle) {
-		b.bbWhiteRook = (b.bbWhiteRook & ^bbForSquare(A1)) | bbForSquare(D1)
-	} else if p1.Color() == Black && m.HasTag(KingSideCastle) {
-		b.bbBlackRook = (b.bbBlackRook & ^bbForSquare(H8) | bbForSquare(F8))
-	} else if p1.Color() == Black && m.HasTag(QueenSideCastle) {
-		b.bbBlackRook = (b.bbBlackRook & ^bbForSquare(A8)) | bbForSquare(D8)
-	}
 	b.calcConvienceBBs(m)
 }
 
+// castle moves the king and rook of a castling move to the g and f
+// files or the c and d files.  Standard castles are encoded as the
+// king's move while Chess960 castles are encoded as the king moving
+// to the square of the rook it castles with.
+func (b *Board) castle(m *Move) {
+	king := b.Piece(m.s1)
+	rook := NewPiece(Rook, king.Color())
+	kingTo, rookSq, rookTo := b.castleRoute(m)
+	b.setBBForPiece(king, b.bbForPiece(king) & ^bbForSquare(m.s1) | bbForSquare(kingTo))
+	b.setBBForPiece(rook, b.bbForPiece(rook) & ^bbForSquare(rookSq) | bbForSquare(rookTo))
+	b.calcConvienceBBs(nil)
+}
+
+// castleRoute returns the king's destination and the rook's origin
+// and destination for a castling move.  The rook's origin is only
+// correct before the move is made.
+func (b *Board) castleRoute(m *Move) (kingTo, rookSq, rookTo Square) {
+	rank := m.s1.Rank()
+	rookSq, kingTo, rookTo = NewSquare(FileH, rank), NewSquare(FileG, rank), NewSquare(FileF, rank)
+	if m.HasTag(QueenSideCastle) {
+		rookSq, kingTo, rookTo = NewSquare(FileA, rank), NewSquare(FileC, rank), NewSquare(FileD, rank)
+	}
+	if b.Piece(m.s2) == NewPiece(Rook, b.Piece(m.s1).Color()) {
+		rookSq = m.s2
+	}
+	return kingTo, rookSq, rookTo
+}
+
+// revert reverses update for a move of the moved piece that captured
+// the captured piece, which is NoPiece for quiet moves and castles.
+// The rook's origin is only used for castles.
+func (b *Board) revert(m *Move, moved, captured Piece, rookSq Square) {
+	s1BB := bbForSquare(m.s1)
+	if m.HasTag(KingSideCastle) || m.HasTag(QueenSideCastle) {
+		rook := NewPiece(Rook, moved.Color())
+		kingTo, _, rookTo := b.castleRoute(m)
+		b.setBBForPiece(moved, b.bbForPiece(moved) & ^bbForSquare(kingTo))
+		b.setBBForPiece(rook, b.bbForPiece(rook) & ^bbForSquare(rookTo))
+		b.setBBForPiece(moved, b.bbForPiece(moved)|s1BB)
+		b.setBBForPiece(rook, b.bbForPiece(rook)|bbForSquare(rookSq))
+		b.calcConvienceBBs(&Move{s1: kingTo, s2: m.s1})
+		return
+	}
+	s2BB := bbForSquare(m.s2)
+	landed := moved
+	if m.promo != NoPieceType {
+		landed = NewPiece(m.promo, moved.Color())
+	}
+	b.setBBForPiece(landed, b.bbForPiece(landed) & ^s2BB)
+	b.setBBForPiece(moved, b.bbForPiece(moved)|s1BB)
+	if captured != NoPiece {
+		capBB := s2BB
+		if m.HasTag(EnPassant) {
+			capBB = s2BB << 8
+			if moved.Color() == Black {
+				capBB = s2BB >> 8
+			}
+		}
+		b.setBBForPiece(captured, b.bbForPiece(captured)|capBB)
+	}
+	b.calcConvienceBBs(&Move{s1: m.s2, s2: m.s1})
+}
+
+// outermostRook returns the file of the color's rook on its back
+// rank that is closest to the edge of the board on the side of its
+// king.  The bool result is false if there is no such rook.
+func (b *Board) outermostRook(c Color, side Side) (File, bool) {
+	rank, king := Rank1, b.whiteKingSq
+	if c == Black {
+		rank, king = Rank8, b.blackKingSq
+	}
+	rook := NewPiece(Rook, c)
+	for i := 0; i < numOfSquaresInRow; i++ {
+		f := File(numOfSquaresInRow - 1 - i)
+		if side == QueenSide {
+			f = File(i)
+		}
+		if king != NoSquare && king.Rank() == rank && f == king.File() {
+			break
+		}
+		if b.Piece(NewSquare(f, rank)) == rook {
+			return f, true
+		}
+	}
+	return FileA, false
+}
+
 func (b *Board) calcConvienceBBs(m *Move) {
 	whiteSqs := b.bbWhiteKing | b.bbWhiteQueen | b.bbWhiteRook | b.bbWhiteBishop | b.bbWhiteKnight | b.bbWhitePawn
 	blackSqs := b.bbBlackKing | b.bbBlackQueen | b.bbBlackRook | b.bbBlackBishop | b.bbBlackKnight | b.bbBlackPawn
@@ -310,19 +401,43 @@ func (b *Board) copy() *Board {
 	}
 }
 
+// samePieces returns true if both boards have the same pieces
+// on the same squares.  It avoids the allocations of comparing
+// the boards' strings.
+func (b *Board) samePieces(b2 *Board) bool {
+	return b.bbWhiteKing == b2.bbWhiteKing &&
+		b.bbWhiteQueen == b2.bbWhiteQueen &&
+		b.bbWhiteRook == b2.bbWhiteRook &&
+		b.bbWhiteBishop == b2.bbWhiteBishop &&
+		b.bbWhiteKnight == b2.bbWhiteKnight &&
+		b.bbWhitePawn == b2.bbWhitePawn &&
+		b.bbBlackKing == b2.bbBlackKing &&
+		b.bbBlackQueen == b2.bbBlackQueen &&
+		b.bbBlackRook == b2.bbBlackRook &&
+		b.bbBlackBishop == b2.bbBlackBishop &&
+		b.bbBlackKnight == b2.bbBlackKnight &&
+		b.bbBlackPawn == b2.bbBlackPawn
+}
+
 func (b *Board) isOccupied(sq Square) bool {
 	return !b.emptySqs.Occupied(sq)
 }
 
 func (b *Board) hasSufficientMaterial() bool {
+	return b.insufficientMaterialReason() == ""
+}
+
+// insufficientMaterialReason returns a description of why neither side
+// can checkmate or an empty string if there is sufficient material.
+func (b *Board) insufficientMaterialReason() string {
 	// queen, rook, or pawn exist
 	if (b.bbWhiteQueen | b.bbWhiteRook | b.bbWhitePawn |
 		b.bbBlackQueen | b.bbBlackRook | b.bbBlackPawn) > 0 {
-		return true
+		return ""
 	}
 	// if king is missing then it is a test
 	if b.bbWhiteKing == 0 || b.bbBlackKing == 0 {
-		return true
+		return ""
 	}
 	count := map[PieceType]int{}
 	pieceMap := b.SquareMap()
@@ -331,15 +446,15 @@ func (b *Board) hasSufficientMaterial() bool {
 	}
 	// 	king versus king
 	if count[Bishop] == 0 && count[Knight] == 0 {
-		return false
+		return "king vs king"
 	}
 	// king and bishop versus king
 	if count[Bishop] == 1 && count[Knight] == 0 {
-		return false
+		return "king and bishop vs king"
 	}
 	// king and knight versus king
 	if count[Bishop] == 0 && count[Knight] == 1 {
-		return false
+		return "king and knight vs king"
 	}
 	// king and bishop(s) versus king and bishop(s) with the bishops on the same colour.
 	if count[Knight] == 0 {
@@ -356,8 +471,57 @@ func (b *Board) hasSufficientMaterial() bool {
 			}
 		}
 		if whiteCount == 0 || blackCount == 0 {
-			return false
+			return "same-colored bishops"
+		}
+	}
+	return ""
+}
+
+// canCheckmate returns true if the color has enough material to
+// checkmate with the help of the opponent.  A lone knight needs an
+// opponent piece other than a queen to block its king, and bishops
+// on a single square color need an opponent piece that can stand on
+// the other color.
+func (b *Board) canCheckmate(c Color) bool {
+	if (b.bbForPiece(NewPiece(Queen, c)) | b.bbForPiece(NewPiece(Rook, c)) | b.bbForPiece(NewPiece(Pawn, c))) > 0 {
+		return true
+	}
+	var knights int
+	bishops := map[Color]int{}
+	opponent := map[Piece]int{}
+	oppBishops := map[Color]int{}
+	for sq, p := range b.SquareMap() {
+		switch {
+		case p == NewPiece(Knight, c):
+			knights++
+		case p == NewPiece(Bishop, c):
+			bishops[sq.color()]++
+		case p.Color() == c.Other():
+			opponent[p]++
+			if p.Type() == Bishop {
+				oppBishops[sq.color()]++
+			}
+		}
+	}
+	minors := knights + bishops[White] + bishops[Black]
+	switch {
+	case minors == 0:
+		return false
+	case knights == 1 && minors == 1:
+		for p := range opponent {
+			if p.Type() != King && p.Type() != Queen {
+				return true
+			}
+		}
+		return false
+	case knights == 0 && (bishops[White] == 0 || bishops[Black] == 0):
+		other := White
+		if bishops[White] > 0 {
+			other = Black
 		}
+		o := c.Other()
+		return oppBishops[other] > 0 || opponent[NewPiece(Knight, o)] > 0 || opponent[NewPiece(Pawn, o)] > 0 ||
+			opponent[NewPiece(Rook, o)] > 0 || opponent[NewPiece(Queen, o)] > 0
 	}
 	return true
 }
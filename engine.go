@@ -1,15 +1,40 @@
 package chess
 
+import (
+	"fmt"
+	"strings"
+)
+
 type engine struct{}
 
 func (engine) CalcMoves(pos *Position, first bool) []*Move {
+	return engine{}.calcFilteredMoves(pos, first, nil)
+}
+
+// calcFilteredMoves returns the valid moves for which keep returns
+// true.  All valid moves are returned if keep is nil.
+func (engine) calcFilteredMoves(pos *Position, first bool, keep func(*Move) bool) []*Move {
+	return engine{}.appendMoves([]*Move{}, pos, first, keep)
+}
+
+// appendMoves appends the valid moves for which keep returns true
+// to moves and returns the extended slice.
+func (engine) appendMoves(moves []*Move, pos *Position, first bool, keep func(*Move) bool) []*Move {
 	// generate possible moves
-	moves := standardMoves(pos, first)
+	moves = standardMoves(moves, pos, first, keep)
 	// return moves including castles
-	return append(moves, castleMoves(pos)...)
+	for _, m := range castleMoves(pos) {
+		if !isIllegal(pos, m) && (keep == nil || keep(m)) {
+			moves = append(moves, m)
+		}
+	}
+	return moves
 }
 
 func (engine) Status(pos *Position) Method {
+	if variantOutcome(pos) != NoOutcome {
+		return VariantEnd
+	}
 	hasMove := false
 	if pos.validMoves != nil {
 		hasMove = len(pos.validMoves) > 0
@@ -28,13 +53,73 @@ var (
 	promoPieceTypes = []PieceType{Queen, Rook, Bishop, Knight}
 )
 
-func standardMoves(pos *Position, first bool) []*Move {
+func (engine) explainIllegal(pos *Position, m *Move) string {
+	p := pos.board.Piece(m.s1)
+	switch {
+	case m.s1 == NoSquare || m.s2 == NoSquare:
+		return "move is missing a square"
+	case p == NoPiece:
+		return fmt.Sprintf("no piece on %s", m.s1)
+	case p.Color() != pos.turn:
+		return "not your turn"
+	case m.s1 == m.s2:
+		return "piece must move to a different square"
+	case p.Type() == King && pos.board.Piece(m.s2) == NewPiece(Rook, p.Color()):
+		return explainIllegalCastle(pos, m)
+	case pos.board.Piece(m.s2).Color() == p.Color():
+		return fmt.Sprintf("%s is occupied by your own piece", m.s2)
+	}
+	if p.Type() == King && bbKingMoves[m.s1]&bbForSquare(m.s2) == 0 {
+		return explainIllegalCastle(pos, m)
+	}
+	if bbForPossibleMoves(pos, p.Type(), m.s1)&bbForSquare(m.s2) == 0 {
+		if bbForEmptyBoardMoves(p.Type(), m.s1)&bbForSquare(m.s2) != 0 {
+			return fmt.Sprintf("path from %s to %s is blocked", m.s1, m.s2)
+		}
+		return fmt.Sprintf("%s on %s cannot move to %s", strings.ToLower(p.Type().Name()), m.s1, m.s2)
+	}
+	lastRank := (p == WhitePawn && m.s2.Rank() == Rank8) || (p == BlackPawn && m.s2.Rank() == Rank1)
+	if lastRank && m.promo == NoPieceType {
+		return "pawn must promote"
+	} else if !lastRank && m.promo != NoPieceType {
+		return "only pawns reaching the last rank can promote"
+	}
+	cp := &Move{s1: m.s1, s2: m.s2, promo: m.promo}
+	addTags(cp, pos)
+	if cp.HasTag(inCheck) {
+		if pos.inCheck {
+			return "move does not resolve check"
+		}
+		return "king would be in check"
+	}
+	if pos.variant == RacingKings && cp.HasTag(Check) {
+		return "move gives check in racing kings"
+	}
+	return "move is invalid"
+}
+
+func explainIllegalCastle(pos *Position, m *Move) string {
+	for _, side := range []Side{KingSide, QueenSide} {
+		king, kingTo, rook, _, _ := castleSquares(pos, pos.turn, side)
+		if m.s1 != king || (m.s2 != kingTo && m.s2 != rook) {
+			continue
+		}
+		if _, reason := castleMove(pos, side); reason == castlePathBlocked {
+			return fmt.Sprintf("path from %s to %s is blocked", m.s1, m.s2)
+		} else if reason != "" {
+			return reason
+		}
+		return "move is invalid"
+	}
+	return fmt.Sprintf("king on %s cannot move to %s", m.s1, m.s2)
+}
+
+func standardMoves(moves []*Move, pos *Position, first bool, keep func(*Move) bool) []*Move {
 	// compute allowed destination bitboard
 	bbAllowed := ^pos.board.whiteSqs
 	if pos.Turn() == Black {
 		bbAllowed = ^pos.board.blackSqs
 	}
-	moves := []*Move{}
 	// iterate through pieces to find possible moves
 	for _, p := range allPieces {
 		if pos.Turn() != p.Color() {
@@ -64,7 +149,7 @@ func standardMoves(pos *Position, first bool) []*Move {
 						m := &Move{s1: Square(s1), s2: Square(s2), promo: pt}
 						addTags(m, pos)
 						// filter out moves that put king into check
-						if !m.HasTag(inCheck) {
+						if !isIllegal(pos, m) && (keep == nil || keep(m)) {
 							moves = append(moves, m)
 							if first {
 								return moves
@@ -75,7 +160,7 @@ func standardMoves(pos *Position, first bool) []*Move {
 					m := &Move{s1: Square(s1), s2: Square(s2)}
 					addTags(m, pos)
 					// filter out moves that put king into check
-					if !m.HasTag(inCheck) {
+					if !isIllegal(pos, m) && (keep == nil || keep(m)) {
 						moves = append(moves, m)
 						if first {
 							return moves
@@ -88,16 +173,24 @@ func standardMoves(pos *Position, first bool) []*Move {
 	return moves
 }
 
+// isIllegal returns true if the move puts the mover's king into check
+// or gives check in Racing Kings.
+func isIllegal(pos *Position, m *Move) bool {
+	return m.HasTag(inCheck) || (pos.variant == RacingKings && m.HasTag(Check))
+}
+
 func addTags(m *Move, pos *Position) {
 	p := pos.board.Piece(m.s1)
-	if pos.board.isOccupied(m.s2) {
+	castle := m.HasTag(KingSideCastle) || m.HasTag(QueenSideCastle)
+	if pos.board.isOccupied(m.s2) && !castle {
 		m.addTag(Capture)
 	} else if m.s2 == pos.enPassantSquare && p.Type() == Pawn {
 		m.addTag(EnPassant)
 	}
 	// determine if in check after move (makes move invalid)
-	cp := pos.copy()
-	cp.board.update(m)
+	b := *pos.board
+	b.update(m)
+	cp := &Position{board: &b, turn: pos.turn}
 	if isInCheck(cp) {
 		m.addTag(inCheck)
 	}
@@ -182,6 +275,35 @@ func squaresAreAttacked(pos *Position, sqs ...Square) bool {
 	return false
 }
 
+// attackersBB returns the bitboard of the given color's pieces
+// that attack the square.  Pieces attacking through other pieces
+// are not included.
+func attackersBB(b *Board, sq Square, c Color) bitboard {
+	return attackersWithOccupancy(b, sq, c, ^b.emptySqs)
+}
+
+// attackersWithOccupancy returns the bitboard of the given color's
+// pieces on occupied squares that attack the square when only the
+// occupied squares block sliding pieces.
+func attackersWithOccupancy(b *Board, sq Square, c Color, occ bitboard) bitboard {
+	dia := diaAttack(occ, sq)
+	hv := hvAttack(occ, sq)
+	bb := (dia | hv) & b.bbForPiece(NewPiece(Queen, c))
+	bb |= hv & b.bbForPiece(NewPiece(Rook, c))
+	bb |= dia & b.bbForPiece(NewPiece(Bishop, c))
+	bb |= bbKnightMoves[sq] & b.bbForPiece(NewPiece(Knight, c))
+	bb |= bbKingMoves[sq] & b.bbForPiece(NewPiece(King, c))
+	sqBB := bbForSquare(sq)
+	if c == White {
+		pawnSqs := ((sqBB & ^bbFileA & ^bbRank1) << 9) | ((sqBB & ^bbFileH & ^bbRank1) << 7)
+		bb |= pawnSqs & b.bbWhitePawn
+	} else {
+		pawnSqs := ((sqBB & ^bbFileA & ^bbRank8) >> 7) | ((sqBB & ^bbFileH & ^bbRank8) >> 9)
+		bb |= pawnSqs & b.bbBlackPawn
+	}
+	return bb & occ
+}
+
 func bbForPossibleMoves(pos *Position, pt PieceType, sq Square) bitboard {
 	switch pt {
 	case King:
@@ -200,54 +322,112 @@ func bbForPossibleMoves(pos *Position, pt PieceType, sq Square) bitboard {
 	return bitboard(0)
 }
 
-// TODO can calc isInCheck twice
+func bbForEmptyBoardMoves(pt PieceType, sq Square) bitboard {
+	switch pt {
+	case Queen:
+		return bbQueenMoves[sq]
+	case Rook:
+		return bbRookMoves[sq]
+	case Bishop:
+		return bbBishopMoves[sq]
+	}
+	return bitboard(0)
+}
+
 func castleMoves(pos *Position) []*Move {
 	moves := []*Move{}
-	kingSide := pos.castleRights.CanCastle(pos.Turn(), KingSide)
-	queenSide := pos.castleRights.CanCastle(pos.Turn(), QueenSide)
-	// white king side
-	if pos.turn == White && kingSide &&
-		(^pos.board.emptySqs&(bbForSquare(F1)|bbForSquare(G1))) == 0 &&
-		!squaresAreAttacked(pos, F1, G1) &&
-		!pos.inCheck {
-		m := &Move{s1: E1, s2: G1}
-		m.addTag(KingSideCastle)
-		addTags(m, pos)
-		moves = append(moves, m)
-	}
-	// white queen side
-	if pos.turn == White && queenSide &&
-		(^pos.board.emptySqs&(bbForSquare(B1)|bbForSquare(C1)|bbForSquare(D1))) == 0 &&
-		!squaresAreAttacked(pos, C1, D1) &&
-		!pos.inCheck {
-		m := &Move{s1: E1, s2: C1}
-		m.addTag(QueenSideCastle)
-		addTags(m, pos)
-		moves = append(moves, m)
-	}
-	// black king side
-	if pos.turn == Black && kingSide &&
-		(^pos.board.emptySqs&(bbForSquare(F8)|bbForSquare(G8))) == 0 &&
-		!squaresAreAttacked(pos, F8, G8) &&
-		!pos.inCheck {
-		m := &Move{s1: E8, s2: G8}
-		m.addTag(KingSideCastle)
-		addTags(m, pos)
-		moves = append(moves, m)
-	}
-	// black queen side
-	if pos.turn == Black && queenSide &&
-		(^pos.board.emptySqs&(bbForSquare(B8)|bbForSquare(C8)|bbForSquare(D8))) == 0 &&
-		!squaresAreAttacked(pos, C8, D8) &&
-		!pos.inCheck {
-		m := &Move{s1: E8, s2: C8}
-		m.addTag(QueenSideCastle)
-		addTags(m, pos)
-		moves = append(moves, m)
+	for _, side := range []Side{KingSide, QueenSide} {
+		if m, _ := castleMove(pos, side); m != nil {
+			moves = append(moves, m)
+		}
 	}
 	return moves
 }
 
+// castleSquares returns the squares of the king and rook before and
+// after the color castles to the side.  The bool result is false if
+// the king isn't on its back rank or the castling rook isn't on the
+// side of the king.
+func castleSquares(pos *Position, c Color, side Side) (king, kingTo, rook, rookTo Square, ok bool) {
+	rank, king := Rank1, pos.board.whiteKingSq
+	if c == Black {
+		rank, king = Rank8, pos.board.blackKingSq
+	}
+	rook = pos.castleRookSquare(c, side)
+	kingTo, rookTo = NewSquare(FileG, rank), NewSquare(FileF, rank)
+	if side == QueenSide {
+		kingTo, rookTo = NewSquare(FileC, rank), NewSquare(FileD, rank)
+	}
+	ok = king != NoSquare && king.Rank() == rank &&
+		pos.board.Piece(rook) == NewPiece(Rook, c) &&
+		(side == KingSide) == (rook.File() > king.File())
+	return king, kingTo, rook, rookTo, ok
+}
+
+// castlePathBlocked is the reason castleMove gives when a piece is in
+// the way.  It is formatted with the move's squares when explained.
+const castlePathBlocked = "path is blocked"
+
+// castleMove returns the castling move to the side for the color to
+// move or the reason castling is illegal.  Every square between the
+// king, the rook, and their destinations must be empty other than the
+// king and rook themselves and the king may not castle out of,
+// through, or into check.  Standard castles are encoded as the king's
+// move and Chess960 castles as the king moving to the rook's square.
+func castleMove(pos *Position, side Side) (*Move, string) {
+	if !pos.castleRights.CanCastle(pos.turn, side) {
+		return nil, "castling rights have been lost"
+	}
+	king, kingTo, rook, rookTo, ok := castleSquares(pos, pos.turn, side)
+	if !ok {
+		return nil, "castling king or rook is missing"
+	}
+	if pos.inCheck {
+		return nil, "cannot castle out of check"
+	}
+	lo, hi := king.File(), king.File()
+	for _, sq := range []Square{kingTo, rook, rookTo} {
+		if sq.File() < lo {
+			lo = sq.File()
+		}
+		if sq.File() > hi {
+			hi = sq.File()
+		}
+	}
+	var path bitboard
+	for f := lo; f <= hi; f++ {
+		path |= bbForSquare(NewSquare(f, king.Rank()))
+	}
+	path &= ^(bbForSquare(king) | bbForSquare(rook))
+	if path&^pos.board.emptySqs != 0 {
+		return nil, castlePathBlocked
+	}
+	step := Square(1)
+	if kingTo < king {
+		step = -1
+	}
+	for sq := king; sq != kingTo; {
+		sq += step
+		if squaresAreAttacked(pos, sq) {
+			return nil, "cannot castle through check"
+		}
+	}
+	m := &Move{s1: king, s2: kingTo}
+	tag, standardFile := KingSideCastle, FileH
+	if side == QueenSide {
+		tag, standardFile = QueenSideCastle, FileA
+	}
+	if king.File() != FileE || rook.File() != standardFile {
+		m.s2 = rook
+	}
+	m.addTag(tag)
+	addTags(m, pos)
+	if m.HasTag(inCheck) {
+		return nil, "king would be in check"
+	}
+	return m, ""
+}
+
 func pawnMoves(pos *Position, sq Square) bitboard {
 	bb := bbForSquare(sq)
 	var bbEnPassant bitboard
@@ -259,6 +439,10 @@ func pawnMoves(pos *Position, sq Square) bitboard {
 		capLeft := ((bb & ^bbFileA & ^bbRank8) >> 7) & (pos.board.blackSqs | bbEnPassant)
 		upOne := ((bb & ^bbRank8) >> 8) & pos.board.emptySqs
 		upTwo := ((upOne & bbRank3) >> 8) & pos.board.emptySqs
+		// horde pawns may also advance two squares from the first rank
+		if pos.variant == Horde {
+			upTwo |= ((upOne & bbRank2) >> 8) & pos.board.emptySqs
+		}
 		return capRight | capLeft | upOne | upTwo
 	}
 	capRight := ((bb & ^bbFileH & ^bbRank1) << 7) & (pos.board.whiteSqs | bbEnPassant)
@@ -330,11 +514,15 @@ var (
 
 	bbKingMoves = [64]bitboard{4665729213955833856, 11592265440851656704, 5796132720425828352, 2898066360212914176, 1449033180106457088, 724516590053228544, 362258295026614272, 144959613005987840, 13853283560024178688, 16186183351374184448, 8093091675687092224, 4046545837843546112, 2023272918921773056, 1011636459460886528, 505818229730443264, 216739030602088448, 54114388906344448, 63227278716305408, 31613639358152704, 15806819679076352, 7903409839538176, 3951704919769088, 1975852459884544, 846636838289408, 211384331665408, 246981557485568, 123490778742784, 61745389371392, 30872694685696, 15436347342848, 7718173671424, 3307175149568, 825720045568, 964771708928, 482385854464, 241192927232, 120596463616, 60298231808, 30149115904, 12918652928, 3225468928, 3768639488, 1884319744, 942159872, 471079936, 235539968, 117769984, 50463488, 12599488, 14721248, 7360624, 3680312, 1840156, 920078, 460039, 197123, 49216, 57504, 28752, 14376, 7188, 3594, 1797, 770}
 
-	bbSquares = [64]bitboard{}
+	// bbSquares is built by an initializer instead of an init function
+	// so that package level positions decoded from FEN can detect check
+	bbSquares = newBBSquares()
 )
 
-func init() {
+func newBBSquares() [64]bitboard {
+	bbs := [64]bitboard{}
 	for sq := 0; sq < 64; sq++ {
-		bbSquares[sq] = bitboard(uint64(1) << (uint8(63) - uint8(sq)))
+		bbs[sq] = bitboard(uint64(1) << (uint8(63) - uint8(sq)))
 	}
+	return bbs
 }
@@ -0,0 +1,171 @@
+package chess
+
+// PieceSquareTable holds a bonus in centipawns for each square from
+// white's perspective.  The table is laid out as the board is viewed
+// by white with A8 first and H1 last.  Black pieces use the vertically
+// mirrored square.
+type PieceSquareTable [64]int
+
+// OpeningPieceValues and EndgamePieceValues are the material values in
+// centipawns used by EvaluateTapered.  They can be changed for tuning.
+var (
+	OpeningPieceValues = map[PieceType]int{
+		Pawn:   100,
+		Knight: 320,
+		Bishop: 330,
+		Rook:   500,
+		Queen:  900,
+	}
+	EndgamePieceValues = map[PieceType]int{
+		Pawn:   120,
+		Knight: 300,
+		Bishop: 330,
+		Rook:   520,
+		Queen:  920,
+	}
+)
+
+// OpeningTables and EndgameTables are the piece square tables used by
+// EvaluateTapered.  They can be changed for tuning and each phase has
+// its own copy of the tables so changing one doesn't change the other.
+var (
+	OpeningTables = map[PieceType]*PieceSquareTable{
+		Pawn:   newTable(pawnOpeningTable),
+		Knight: newTable(knightTable),
+		Bishop: newTable(bishopTable),
+		Rook:   newTable(rookTable),
+		Queen:  newTable(queenTable),
+		King:   newTable(kingOpeningTable),
+	}
+	EndgameTables = map[PieceType]*PieceSquareTable{
+		Pawn:   newTable(pawnEndgameTable),
+		Knight: newTable(knightTable),
+		Bishop: newTable(bishopTable),
+		Rook:   newTable(rookTable),
+		Queen:  newTable(queenTable),
+		King:   newTable(kingEndgameTable),
+	}
+)
+
+// EvaluateTapered returns a static evaluation of the position in
+// centipawns from white's perspective.  Material and piece square
+// table scores for the opening and the endgame are interpolated by
+// the position's PhaseValue.
+func (pos *Position) EvaluateTapered() int {
+	opening, endgame := 0, 0
+	for sq, p := range pos.board.SquareMap() {
+		i := pstIndex(sq, p.Color())
+		o, e := OpeningPieceValues[p.Type()], EndgamePieceValues[p.Type()]
+		if t := OpeningTables[p.Type()]; t != nil {
+			o += t[i]
+		}
+		if t := EndgameTables[p.Type()]; t != nil {
+			e += t[i]
+		}
+		if p.Color() == Black {
+			o, e = -o, -e
+		}
+		opening += o
+		endgame += e
+	}
+	phase := pos.PhaseValue()
+	return (opening*phase + endgame*(MaxPhaseValue-phase)) / MaxPhaseValue
+}
+
+// pstIndex returns the index in a PieceSquareTable for the square
+// from the perspective of the given color.
+func pstIndex(sq Square, c Color) int {
+	r := int(sq.Rank())
+	if c == White {
+		r = 7 - r
+	}
+	return r*8 + int(sq.File())
+}
+
+// newTable returns a pointer to a copy of the table.
+func newTable(t PieceSquareTable) *PieceSquareTable {
+	return &t
+}
+
+var (
+	pawnOpeningTable = PieceSquareTable{
+		0, 0, 0, 0, 0, 0, 0, 0,
+		50, 50, 50, 50, 50, 50, 50, 50,
+		10, 10, 20, 30, 30, 20, 10, 10,
+		5, 5, 10, 25, 25, 10, 5, 5,
+		0, 0, 0, 20, 20, 0, 0, 0,
+		5, -5, -10, 0, 0, -10, -5, 5,
+		5, 10, 10, -20, -20, 10, 10, 5,
+		0, 0, 0, 0, 0, 0, 0, 0,
+	}
+	pawnEndgameTable = PieceSquareTable{
+		0, 0, 0, 0, 0, 0, 0, 0,
+		80, 80, 80, 80, 80, 80, 80, 80,
+		50, 50, 50, 50, 50, 50, 50, 50,
+		30, 30, 30, 30, 30, 30, 30, 30,
+		20, 20, 20, 20, 20, 20, 20, 20,
+		10, 10, 10, 10, 10, 10, 10, 10,
+		10, 10, 10, 10, 10, 10, 10, 10,
+		0, 0, 0, 0, 0, 0, 0, 0,
+	}
+	knightTable = PieceSquareTable{
+		-50, -40, -30, -30, -30, -30, -40, -50,
+		-40, -20, 0, 0, 0, 0, -20, -40,
+		-30, 0, 10, 15, 15, 10, 0, -30,
+		-30, 5, 15, 20, 20, 15, 5, -30,
+		-30, 0, 15, 20, 20, 15, 0, -30,
+		-30, 5, 10, 15, 15, 10, 5, -30,
+		-40, -20, 0, 5, 5, 0, -20, -40,
+		-50, -40, -30, -30, -30, -30, -40, -50,
+	}
+	bishopTable = PieceSquareTable{
+		-20, -10, -10, -10, -10, -10, -10, -20,
+		-10, 0, 0, 0, 0, 0, 0, -10,
+		-10, 0, 5, 10, 10, 5, 0, -10,
+		-10, 5, 5, 10, 10, 5, 5, -10,
+		-10, 0, 10, 10, 10, 10, 0, -10,
+		-10, 10, 10, 10, 10, 10, 10, -10,
+		-10, 5, 0, 0, 0, 0, 5, -10,
+		-20, -10, -10, -10, -10, -10, -10, -20,
+	}
+	rookTable = PieceSquareTable{
+		0, 0, 0, 0, 0, 0, 0, 0,
+		5, 10, 10, 10, 10, 10, 10, 5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		0, 0, 0, 5, 5, 0, 0, 0,
+	}
+	queenTable = PieceSquareTable{
+		-20, -10, -10, -5, -5, -10, -10, -20,
+		-10, 0, 0, 0, 0, 0, 0, -10,
+		-10, 0, 5, 5, 5, 5, 0, -10,
+		-5, 0, 5, 5, 5, 5, 0, -5,
+		0, 0, 5, 5, 5, 5, 0, -5,
+		-10, 5, 5, 5, 5, 5, 0, -10,
+		-10, 0, 5, 0, 0, 0, 0, -10,
+		-20, -10, -10, -5, -5, -10, -10, -20,
+	}
+	kingOpeningTable = PieceSquareTable{
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-20, -30, -30, -40, -40, -30, -30, -20,
+		-10, -20, -20, -20, -20, -20, -20, -10,
+		20, 20, 0, 0, 0, 0, 20, 20,
+		20, 30, 10, 0, 0, 10, 30, 20,
+	}
+	kingEndgameTable = PieceSquareTable{
+		-50, -40, -30, -20, -20, -30, -40, -50,
+		-30, -20, -10, 0, 0, -10, -20, -30,
+		-30, -10, 20, 30, 30, 20, -10, -30,
+		-30, -10, 30, 40, 40, 30, -10, -30,
+		-30, -10, 30, 40, 40, 30, -10, -30,
+		-30, -10, 20, 30, 30, 20, -10, -30,
+		-30, -30, 0, 0, 0, 0, -30, -30,
+		-50, -30, -30, -30, -30, -30, -30, -50,
+	}
+)
@@ -0,0 +1,34 @@
+package chess
+
+import "testing"
+
+func TestEvaluateTapered(t *testing.T) {
+	if v := StartingPosition().EvaluateTapered(); v != 0 {
+		t.Fatalf("expected symmetric starting position to evaluate to 0 but got %d", v)
+	}
+	center := unsafeFEN("4k3/8/8/8/3N4/8/8/4K3 w - - 0 1")
+	rim := unsafeFEN("4k3/8/8/8/N7/8/8/4K3 w - - 0 1")
+	if center.EvaluateTapered() <= rim.EvaluateTapered() {
+		t.Fatalf("expected centralized knight %d to score higher than rim knight %d",
+			center.EvaluateTapered(), rim.EvaluateTapered())
+	}
+	black := unsafeFEN("4k3/8/8/3n4/8/8/8/4K3 w - - 0 1")
+	if black.EvaluateTapered() != -center.EvaluateTapered() {
+		t.Fatalf("expected mirrored black knight to score %d but got %d",
+			-center.EvaluateTapered(), black.EvaluateTapered())
+	}
+}
+
+func TestEvaluateTaperedTablesAreSeparate(t *testing.T) {
+	for _, pt := range []PieceType{Pawn, Knight, Bishop, Rook, Queen, King} {
+		if OpeningTables[pt] == EndgameTables[pt] {
+			t.Fatalf("expected %s tables to be separate copies", pt)
+		}
+	}
+	old := OpeningTables[Knight][0]
+	defer func() { OpeningTables[Knight][0] = old }()
+	OpeningTables[Knight][0] = old + 100
+	if EndgameTables[Knight][0] != old {
+		t.Fatalf("expected endgame knight table to be unchanged but got %d", EndgameTables[Knight][0])
+	}
+}
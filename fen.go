@@ -1,11 +1,108 @@
 package chess
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"strconv"
 	"strings"
 )
 
+// PositionsFromReader returns the positions from the reader which
+// contains one FEN or EPD record per line.  Blank lines and lines
+// starting with # are skipped.  EPD operations are ignored and EPD
+// records without move counters default to 0 1.  Lines that fail to
+// parse don't stop the reader, instead an error including the line
+// number is returned for each of them.
+func PositionsFromReader(r io.Reader) ([]*Position, []error) {
+	positions := []*Position{}
+	errs := []error{}
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		pos, err := decodeFENOrEPD(line)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("chess: line %d: %w", lineNum, err))
+			continue
+		}
+		positions = append(positions, pos)
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	return positions, errs
+}
+
+func decodeFENOrEPD(line string) (*Position, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 {
+		return nil, fmt.Errorf("chess: fen invalid notiation %s must have at least 4 sections", line)
+	}
+	if len(fields) >= 6 && isDigits(fields[4]) && isDigits(fields[5]) {
+		return decodeFEN(strings.Join(fields[:6], " "))
+	}
+	return decodeFEN(strings.Join(fields[:4], " ") + " 0 1")
+}
+
+func isDigits(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
+// DrawFEN returns a text diagram of the board described by the FEN
+// in the format of Board's Draw method.  An error is returned if the
+// FEN can't be parsed.
+func DrawFEN(fen string) (string, error) {
+	pos, err := decodeFEN(fen)
+	if err != nil {
+		return "", err
+	}
+	return pos.board.Draw(), nil
+}
+
+// FENFields is a bitmask of the six FEN fields used to select the
+// fields returned by Position's FEN method.
+type FENFields uint8
+
+const (
+	// FENPlacement is the piece placement field.
+	FENPlacement FENFields = 1 << iota
+	// FENTurn is the active color field.
+	FENTurn
+	// FENCastling is the castling rights field.
+	FENCastling
+	// FENEnPassant is the en passant square field.
+	FENEnPassant
+	// FENHalfMoveClock is the half move clock field.
+	FENHalfMoveClock
+	// FENMoveCount is the full move number field.
+	FENMoveCount
+	// FENAll includes all six fields.
+	FENAll = FENPlacement | FENTurn | FENCastling | FENEnPassant | FENHalfMoveClock | FENMoveCount
+)
+
+// FEN returns the position's FEN notation with only the selected
+// fields separated by spaces.  Fields are always in FEN order and
+// all six fields are returned if fields is zero.
+func (pos *Position) FEN(fields FENFields) string {
+	if fields == 0 {
+		fields = FENAll
+	}
+	parts := strings.Split(pos.String(), " ")
+	selected := []string{}
+	for i, part := range parts {
+		if fields&(1<<uint(i)) != 0 {
+			selected = append(selected, part)
+		}
+	}
+	return strings.Join(selected, " ")
+}
+
 // Decodes FEN notation into a GameState.  An error is returned
 // if there is a parsing error.  FEN notation format:
 // rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1
@@ -23,7 +120,7 @@ func decodeFEN(fen string) (*Position, error) {
 	if !ok {
 		return nil, fmt.Errorf("chess: fen invalid turn %s", parts[1])
 	}
-	rights, err := formCastleRights(parts[2])
+	rights, files, err := fenCastleRights(parts[2], b)
 	if err != nil {
 		return nil, err
 	}
@@ -39,14 +136,41 @@ func decodeFEN(fen string) (*Position, error) {
 	if err != nil || moveCount < 1 {
 		return nil, fmt.Errorf("chess: fen invalid move count %s", parts[5])
 	}
-	return &Position{
+	pos := &Position{
 		board:           b,
 		turn:            turn,
 		castleRights:    rights,
+		rookFiles:       files,
 		enPassantSquare: sq,
 		halfMoveClock:   halfMoveClock,
 		moveCount:       moveCount,
-	}, nil
+	}
+	if err := validateEnPassant(pos); err != nil {
+		return nil, err
+	}
+	pos.inCheck = isInCheck(pos)
+	return pos, nil
+}
+
+// validateEnPassant returns an error if the position's en passant
+// square couldn't have been created by a pawn's double push on the
+// previous move.
+func validateEnPassant(pos *Position) error {
+	sq := pos.enPassantSquare
+	if sq == NoSquare {
+		return nil
+	}
+	rank, pawnRank, pawn := Rank6, Rank5, BlackPawn
+	if pos.turn == Black {
+		rank, pawnRank, pawn = Rank3, Rank4, WhitePawn
+	}
+	if sq.Rank() != rank {
+		return fmt.Errorf("chess: fen invalid en passant square %s for %s to move", sq, strings.ToLower(pos.turn.Name()))
+	}
+	if pos.board.Piece(NewSquare(sq.File(), pawnRank)) != pawn {
+		return fmt.Errorf("chess: fen invalid en passant square %s without a %s pawn on %s", sq, strings.ToLower(pawn.Color().Name()), NewSquare(sq.File(), pawnRank))
+	}
+	return nil
 }
 
 // generates board from fen format: rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR
@@ -111,6 +235,71 @@ func formCastleRights(castleStr string) (CastleRights, error) {
 	return CastleRights(castleStr), nil
 }
 
+// fenCastleRights decodes a FEN castling field in the standard (KQkq),
+// X-FEN, or Shredder-FEN (HAha) forms.  K and Q name the outermost
+// rook on that side of the king while file letters name the file of
+// the castling rook as in Chess960.  The rook files are nil if they
+// are the standard a and h files.
+func fenCastleRights(castleStr string, b *Board) (CastleRights, *rookFiles, error) {
+	if castleStr == "-" {
+		return "-", nil, nil
+	}
+	err := fmt.Errorf("chess: fen invalid castle rights %s", castleStr)
+	files := rookFiles{{FileH, FileA}, {FileH, FileA}}
+	granted := map[string]bool{}
+	for _, r := range castleStr {
+		c, upper := White, r
+		if r >= 'a' && r <= 'z' {
+			c, upper = Black, r-'a'+'A'
+		}
+		king := b.whiteKingSq
+		rank := Rank1
+		if c == Black {
+			king, rank = b.blackKingSq, Rank8
+		}
+		var side Side
+		var file File
+		switch {
+		case upper == 'K' || upper == 'Q':
+			side = KingSide
+			if upper == 'Q' {
+				side = QueenSide
+			}
+			file = files[c-White][side-KingSide]
+			if f, ok := b.outermostRook(c, side); ok {
+				file = f
+			}
+		case upper >= 'A' && upper <= 'H':
+			file = File(upper - 'A')
+			if king == NoSquare || king.Rank() != rank || king.File() == file {
+				return "-", nil, err
+			}
+			side = QueenSide
+			if file > king.File() {
+				side = KingSide
+			}
+		default:
+			return "-", nil, err
+		}
+		char := castleRightChar(c, side)
+		if granted[char] {
+			return "-", nil, err
+		}
+		granted[char] = true
+		files[c-White][side-KingSide] = file
+	}
+	rights := ""
+	for _, r := range castleRightChars {
+		if granted[r.char] {
+			rights += r.char
+		}
+	}
+	if files == (rookFiles{{FileH, FileA}, {FileH, FileA}}) {
+		return CastleRights(rights), nil, nil
+	}
+	return CastleRights(rights), &files, nil
+}
+
 func formEnPassant(enPassant string) (Square, error) {
 	if enPassant == "-" {
 		return NoSquare, nil
@@ -1,6 +1,9 @@
 package chess
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 var (
 	validFENs = []string{
@@ -30,6 +33,8 @@ var (
 		"8/8/8/8/4k3/8/3KP3/8 c - - 0 1",
 		"8/8/5k2/8/5K2/8/4P3P/8 w - - 0 1",
 		"r4rk1/1b2bppp/ppq1p3/2pp3n/5P2/1P1BP3/PBPPQ1PP/R4RK1 w e4 - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR b KQkq e3 0 1",
+		"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e3 0 1",
 	}
 )
 
@@ -52,3 +57,127 @@ func TestInvalidFENs(t *testing.T) {
 		}
 	}
 }
+
+func TestFENEnPassantWithoutPawn(t *testing.T) {
+	_, err := decodeFEN("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR b KQkq e3 0 1")
+	if err == nil || !strings.Contains(err.Error(), "without a white pawn on e4") {
+		t.Fatalf("expected missing pawn en passant error but got %v", err)
+	}
+	if _, err := decodeFEN("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPositionFENFields(t *testing.T) {
+	pos := unsafeFEN("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1")
+	tests := []struct {
+		fields   FENFields
+		expected string
+	}{
+		{FENPlacement, "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR"},
+		{FENPlacement | FENTurn, "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b"},
+		{FENPlacement | FENTurn | FENCastling | FENEnPassant, "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3"},
+		{FENAll, "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"},
+		{0, "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"},
+	}
+	for _, test := range tests {
+		if s := pos.FEN(test.fields); s != test.expected {
+			t.Fatalf("expected fen fields %d to be %s but got %s", test.fields, test.expected, s)
+		}
+	}
+}
+
+func TestDrawFEN(t *testing.T) {
+	s, err := DrawFEN("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "\n A B C D E F G H\n" +
+		"8♜ - - - ♚ - - ♜ \n" +
+		"7♟ - ♟ ♟ ♛ ♟ ♝ - \n" +
+		"6♝ ♞ - - ♟ ♞ ♟ - \n" +
+		"5- - - ♙ ♘ - - - \n" +
+		"4- ♟ - - ♙ - - - \n" +
+		"3- - ♘ - - ♕ - ♟ \n" +
+		"2♙ ♙ ♙ ♗ ♗ ♙ ♙ ♙ \n" +
+		"1♖ - - - ♔ - - ♖ \n"
+	if s != expected {
+		t.Fatalf("expected diagram %s but got %s", expected, s)
+	}
+	if _, err := DrawFEN("not a fen"); err == nil {
+		t.Fatal("expected error drawing an invalid fen")
+	}
+}
+
+func TestPositionsFromReader(t *testing.T) {
+	suite := `# test suite
+rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1
+rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1
+
+r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - bm Bb5; id "ruy";
+rnbqkbnr/pppppppp/8/8/4P2/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1
+8/8/8/4k3/8/8/8/R3K2R w KQ - 0 1
+`
+	positions, errs := PositionsFromReader(strings.NewReader(suite))
+	if len(positions) != 4 {
+		t.Fatalf("expected %d positions but got %d", 4, len(positions))
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "line 6") {
+		t.Fatalf("expected a single error on line 6 but got %v", errs)
+	}
+	expected := "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 0 1"
+	if positions[2].String() != expected {
+		t.Fatalf("expected epd position %s but got %s", expected, positions[2])
+	}
+}
+
+func TestShredderFENCastleRights(t *testing.T) {
+	pos, err := decodeFEN("r3k2r/8/8/8/8/8/8/R3K2R w HAa - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pos.CastleRights() != "KQq" {
+		t.Fatalf("expected castle rights KQq but got %s", pos.CastleRights())
+	}
+	if !pos.CanCastle(White, KingSide) || !pos.CanCastle(White, QueenSide) {
+		t.Fatal("expected white to be able to castle both sides")
+	}
+	// Chess960 rook files are kept with the castle rights
+	pos, err = decodeFEN("rk4r1/8/8/8/8/8/8/RK4R1 w GAga - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pos.CastleRights() != "KQkq" || pos.String() != "rk4r1/8/8/8/8/8/8/RK4R1 w KQkq - 0 1" {
+		t.Fatalf("expected chess960 castle rights KQkq but got %s", pos)
+	}
+	// the king would castle into check from the rook on g8
+	if pos.CanCastle(White, KingSide) {
+		t.Fatal("expected white not to be able to castle king side")
+	}
+	m, ok := pos.CastleMove(White, QueenSide)
+	if !ok || m.String() != "b1a1" {
+		t.Fatalf("expected queen side castle b1a1 but got %v", m)
+	}
+	expected := "rk4r1/8/8/8/8/8/8/2KR2R1 b kq - 1 1"
+	if s := pos.Update(m).String(); s != expected {
+		t.Fatalf("expected %s after castling but got %s", expected, s)
+	}
+	uci, err := UCINotation{}.Decode(pos, "b1a1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := pos.Update(uci).String(); s != expected {
+		t.Fatalf("expected %s after castling with uci notation but got %s", expected, s)
+	}
+	// an inner rook is written with its file
+	pos, err = decodeFEN("4k3/8/8/8/8/8/8/R3KR1R w F - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := pos.String(); s != "4k3/8/8/8/8/8/8/R3KR1R w F - 0 1" {
+		t.Fatalf("expected the castle rights to keep the f file but got %s", s)
+	}
+	if _, err := decodeFEN("4k3/8/8/8/8/8/8/R3K2R w KH - 0 1"); err == nil {
+		t.Fatal("expected an error for duplicate castle rights")
+	}
+}
@@ -1,12 +1,20 @@
 package chess
 
 import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"strings"
 )
 
+// ErrGameOver is returned when a game that already has an outcome
+// is modified.
+var ErrGameOver = errors.New("chess: game is already over")
+
 // A Outcome is the result of a game.
 type Outcome string
 
@@ -55,6 +63,19 @@ const (
 	// InsufficientMaterial indicates that the game was automatically drawn
 	// because there was insufficient material for checkmate.
 	InsufficientMaterial
+	// Forfeit indicates that the game was won because the opponent
+	// failed to provide a valid move.
+	Forfeit
+	// Timeout indicates that the game was won because the opponent
+	// ran out of time.
+	Timeout
+	// Abandoned indicates that the game was won because the opponent
+	// abandoned the game, such as by disconnecting from a server.
+	Abandoned
+	// VariantEnd indicates that the game ended by a rule of its variant
+	// such as a king reaching the eighth rank in Racing Kings or White
+	// losing all of its pieces in Horde.
+	VariantEnd
 )
 
 // TagPair represents metadata in a key value pairing used in the PGN format.
@@ -65,15 +86,23 @@ type TagPair struct {
 
 // A Game represents a single chess game.
 type Game struct {
-	notation             Notation
+	inputNotation        Decoder
+	outputNotation       Encoder
 	tagPairs             []*TagPair
 	moves                []*Move
 	comments             [][]string
+	variations           [][]string
+	nags                 [][]int
 	positions            []*Position
 	pos                  *Position
 	outcome              Outcome
 	method               Method
 	ignoreAutomaticDraws bool
+	discardComments      bool
+	defaultPromotion     PieceType
+	maxPlies             int
+	onMaxPlies           func(*Game)
+	maxPliesReached      bool
 }
 
 // PGN takes a reader and returns a function that updates
@@ -99,14 +128,24 @@ func PGN(r io.Reader) (func(*Game), error) {
 // the game to reflect the FEN data.  Since FEN doesn't encode
 // prior moves, the move list will be empty.  The returned
 // function is designed to be used in the NewGame constructor.
-// An error is returned if there is a problem parsing the FEN data.
+// An error is returned if there is a problem parsing the FEN data
+// or if either color doesn't have exactly one king.
 func FEN(fen string) (func(*Game), error) {
+	return variantFEN(Standard, fen)
+}
+
+// variantFEN is like FEN for a position played by the rules of the
+// variant.
+func variantFEN(v Variant, fen string) (func(*Game), error) {
 	pos, err := decodeFEN(fen)
 	if err != nil {
 		return nil, err
 	}
+	pos.variant = v
+	if err := pos.hasLegalKings(); err != nil {
+		return nil, err
+	}
 	return func(g *Game) {
-		pos.inCheck = isInCheck(pos)
 		g.pos = pos
 		g.positions = []*Position{pos}
 		g.updatePosition()
@@ -129,7 +168,66 @@ func TagPairs(tagPairs []*TagPair) func(*Game) {
 // to be used in the NewGame constructor.
 func UseNotation(n Notation) func(*Game) {
 	return func(g *Game) {
-		g.notation = n
+		g.inputNotation = n
+		g.outputNotation = n
+	}
+}
+
+// UseInputNotation returns a function that sets the notation
+// used to parse the string supplied to the MoveStr() method
+// without changing the notation used for PGN output.  The
+// returned function is designed to be used in the NewGame
+// constructor.
+func UseInputNotation(n Decoder) func(*Game) {
+	return func(g *Game) {
+		g.inputNotation = n
+	}
+}
+
+// UseOutputNotation returns a function that sets the notation
+// used for PGN output without changing the notation used to
+// parse the string supplied to the MoveStr() method.  The
+// returned function is designed to be used in the NewGame
+// constructor.
+func UseOutputNotation(n Encoder) func(*Game) {
+	return func(g *Game) {
+		g.outputNotation = n
+	}
+}
+
+// DiscardComments returns a function that removes the game's
+// comments, variations, and NAGs and prevents games loaded afterwards
+// with the PGN option from keeping them.  It reduces memory use
+// when loading large databases.  The returned function is designed
+// to be used in the NewGame constructor.
+func DiscardComments() func(*Game) {
+	return func(g *Game) {
+		g.discardComments = true
+		g.comments = nil
+		g.variations = nil
+		g.nags = nil
+	}
+}
+
+// DefaultPromotion returns a function that makes MoveStr promote to
+// the given piece type when a pawn move to the last rank doesn't
+// include a promotion.  By default such moves are rejected.  The
+// returned function is designed to be used in the NewGame constructor.
+func DefaultPromotion(pt PieceType) func(*Game) {
+	return func(g *Game) {
+		g.defaultPromotion = pt
+	}
+}
+
+// MaxPlies returns a function that limits the game to n moves.  Once
+// the nth move is played onExceed is called, if it isn't nil, and any
+// further calls to Move return an error.  The callback is only called
+// once per game.  The returned function is designed to be used in the
+// NewGame constructor.
+func MaxPlies(n int, onExceed func(*Game)) func(*Game) {
+	return func(g *Game) {
+		g.maxPlies = n
+		g.onMaxPlies = onExceed
 	}
 }
 
@@ -139,12 +237,13 @@ func UseNotation(n Notation) func(*Game) {
 func NewGame(options ...func(*Game)) *Game {
 	pos := StartingPosition()
 	game := &Game{
-		notation:  AlgebraicNotation{},
-		moves:     []*Move{},
-		pos:       pos,
-		positions: []*Position{pos},
-		outcome:   NoOutcome,
-		method:    NoMethod,
+		inputNotation:  AlgebraicNotation{},
+		outputNotation: AlgebraicNotation{},
+		moves:          []*Move{},
+		pos:            pos,
+		positions:      []*Position{pos},
+		outcome:        NoOutcome,
+		method:         NoMethod,
 	}
 	for _, f := range options {
 		if f != nil {
@@ -157,7 +256,13 @@ func NewGame(options ...func(*Game)) *Game {
 // Move updates the game with the given move.  An error is returned
 // if the move is invalid or the game has already been completed.
 func (g *Game) Move(m *Move) error {
-	valid := moveSlice(g.ValidMoves()).find(m)
+	if err := g.ensureInProgress(); err != nil {
+		return err
+	}
+	if g.maxPlies > 0 && len(g.moves) >= g.maxPlies {
+		return fmt.Errorf("chess: move %s exceeds the game's limit of %d moves", m, g.maxPlies)
+	}
+	valid := moveSlice(g.pos.validMovesRef()).find(m)
 	if valid == nil {
 		return fmt.Errorf("chess: invalid move %s", m)
 	}
@@ -165,6 +270,12 @@ func (g *Game) Move(m *Move) error {
 	g.pos = g.pos.Update(valid)
 	g.positions = append(g.positions, g.pos)
 	g.updatePosition()
+	if g.maxPlies > 0 && len(g.moves) >= g.maxPlies && !g.maxPliesReached {
+		g.maxPliesReached = true
+		if g.onMaxPlies != nil {
+			g.onMaxPlies(g)
+		}
+	}
 	return nil
 }
 
@@ -175,43 +286,206 @@ func (g *Game) UnMove() error {
 	// remove last g.moves
 	g.moves = g.moves[:len(g.moves)-1]
 	g.positions = g.positions[:len(g.positions)-1]
+	if len(g.moves) < g.maxPlies {
+		g.maxPliesReached = false
+	}
 	g.pos = g.positions[len(g.positions)-1]
 	g.updatePosition()
 	return nil
 }
 
-// MoveStr decodes the given string in game's notation
+// RewindTo rewinds the game to the latest ply where the position
+// matches the given position, ignoring the move counters.  Moves
+// played after that ply are discarded.  An error is returned if
+// the position never occurred in the game.
+func (g *Game) RewindTo(pos *Position) error {
+	for i := len(g.positions) - 1; i >= 0; i-- {
+		if g.positions[i].samePosition(pos) {
+			g.truncate(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("chess: position %s did not occur in the game", pos)
+}
+
+// TruncateTo discards the moves played after the given ply along
+// with their positions, comments, and variations.  Ply 0 is the
+// initial position.  The outcome is recomputed for the remaining
+// moves so a resignation or draw after the ply is cleared.  An error
+// is returned if the ply is out of range.
+func (g *Game) TruncateTo(ply int) error {
+	if ply < 0 || ply > len(g.moves) {
+		return fmt.Errorf("chess: can't truncate game with %d moves to ply %d", len(g.moves), ply)
+	}
+	g.truncate(ply)
+	return nil
+}
+
+// MoveStr decodes the given string in game's input notation
 // and calls the Move function.  An error is returned if
 // the move can't be decoded or the move is invalid.
 func (g *Game) MoveStr(s string) error {
-	m, err := g.notation.Decode(g.pos, s)
+	m, err := g.inputNotation.Decode(g.pos, s)
+	if err != nil && g.defaultPromotion != NoPieceType {
+		m, err = g.decodeWithDefaultPromotion(s, err)
+	}
 	if err != nil {
 		return err
 	}
+	if g.defaultPromotion != NoPieceType && m.promo == NoPieceType && g.IsPromotionMove(m.s1, m.s2) {
+		m = m.WithPromo(g.defaultPromotion)
+	}
 	return g.Move(m)
 }
 
+// MoveSquares plays the valid move between the given squares.  The
+// promotion piece type is ignored unless the move is a promotion in
+// which case it falls back to the game's default promotion when it
+// is NoPieceType.  An error is returned if there is no such move.
+func (g *Game) MoveSquares(from, to Square, promo PieceType) error {
+	if !g.IsPromotionMove(from, to) {
+		promo = NoPieceType
+	} else if promo == NoPieceType {
+		promo = g.defaultPromotion
+	}
+	for _, m := range g.pos.validMovesRef() {
+		if m.s1 == from && m.s2 == to && m.promo == promo {
+			return g.Move(m)
+		}
+	}
+	return fmt.Errorf("chess: invalid move from %s to %s", from, to)
+}
+
+// decodeWithDefaultPromotion decodes s with the default promotion
+// appended in the styles used by the supported notations.  The
+// original error is returned if none of them decode.
+func (g *Game) decodeWithDefaultPromotion(s string, err error) (*Move, error) {
+	base := strings.TrimRight(s, "+#")
+	suffix := s[len(base):]
+	lower := g.defaultPromotion.String()
+	upper := strings.ToUpper(lower)
+	for _, candidate := range []string{base + "=" + upper, base + upper, base + lower} {
+		if m, decodeErr := g.inputNotation.Decode(g.pos, candidate+suffix); decodeErr == nil {
+			return m, nil
+		}
+	}
+	return nil, err
+}
+
 // ValidMoves returns a list of valid moves in the
 // current position.
 func (g *Game) ValidMoves() []*Move {
 	return g.pos.ValidMoves()
 }
 
+// IsPromotionMove returns true if moving from one square to another
+// is a valid pawn promotion in the current position.  Interactive
+// programs can use it to prompt for the promotion piece type before
+// completing the move with Move's WithPromo method.
+func (g *Game) IsPromotionMove(from, to Square) bool {
+	for _, m := range g.pos.validMovesRef() {
+		if m.s1 == from && m.s2 == to && m.promo != NoPieceType {
+			return true
+		}
+	}
+	return false
+}
+
 // Positions returns the position history of the game.
 func (g *Game) Positions() []*Position {
 	return append([]*Position(nil), g.positions...)
 }
 
+// ForEachPosition calls fn for each position in the game starting
+// with the initial position (ply 0) through the current position.
+// Iteration stops early if fn returns false.
+func (g *Game) ForEachPosition(fn func(ply int, pos *Position) bool) {
+	for i, pos := range g.positions {
+		if !fn(i, pos) {
+			return
+		}
+	}
+}
+
+// PositionHashes returns a hash for each position in the game's
+// position history in order.  Each hash is the first eight bytes of
+// the position's Hash so positions reached by transposition share a
+// value regardless of their move counters.
+func (g *Game) PositionHashes() []uint64 {
+	hashes := make([]uint64, len(g.positions))
+	for i, pos := range g.positions {
+		h := pos.Hash()
+		hashes[i] = binary.BigEndian.Uint64(h[:8])
+	}
+	return hashes
+}
+
 // Moves returns the move history of the game.
 func (g *Game) Moves() []*Move {
 	return append([]*Move(nil), g.moves...)
 }
 
+// MovesUCI returns the move history of the game encoded in UCI
+// notation.  This is useful for sending the game to an engine with
+// the "position startpos moves" command.
+func (g *Game) MovesUCI() []string {
+	moves := make([]string, len(g.moves))
+	for i, m := range g.moves {
+		moves[i] = UCINotation{}.Encode(g.positions[i], m)
+	}
+	return moves
+}
+
+// ChecksGiven returns the number of moves by each color that gave
+// check, as counted by the Three-check variant.
+func (g *Game) ChecksGiven() (white, black int) {
+	for i := range g.moves {
+		if !g.positions[i+1].inCheck {
+			continue
+		}
+		if g.positions[i].turn == White {
+			white++
+		} else {
+			black++
+		}
+	}
+	return white, black
+}
+
+// UCIPositionCommand returns the UCI position command for the game's
+// current position such as "position startpos moves e2e4 e7e5".
+// Games that don't begin from the standard starting position use
+// "position fen <fen> moves ...".
+func (g *Game) UCIPositionCommand() string {
+	s := "position startpos"
+	if start := g.positions[0].String(); start != startFEN {
+		s = "position fen " + start
+	}
+	if len(g.moves) > 0 {
+		s += " moves " + strings.Join(g.MovesUCI(), " ")
+	}
+	return s
+}
+
+// PlyCount returns the number of half moves (plies) played in the
+// game.  Unlike the position's move count, which is the full move
+// number used in FEN, the ply count increments after every move.
+func (g *Game) PlyCount() int {
+	return len(g.moves)
+}
+
 // Comments returns the comments for the game indexed by moves.
 func (g *Game) Comments() [][]string {
 	return append([][]string(nil), g.comments...)
 }
 
+// Variations returns the variations for the game indexed by moves.
+// Each variation is an alternative to the move at the same index
+// encoded in algebraic notation with move numbers.
+func (g *Game) Variations() [][]string {
+	return append([][]string(nil), g.variations...)
+}
+
 // TagPairs returns the game's tag pairs.
 func (g *Game) TagPairs() []*TagPair {
 	return append([]*TagPair(nil), g.tagPairs...)
@@ -232,17 +506,101 @@ func (g *Game) Method() Method {
 	return g.method
 }
 
+// Termination returns the PGN Termination tag value for the game's
+// method: "Normal", "Time forfeit", "Rules infraction", or
+// "Abandoned".  An empty string is returned if the method is unknown.
+func (g *Game) Termination() string {
+	switch g.method {
+	case NoMethod:
+		return ""
+	case Timeout:
+		return "Time forfeit"
+	case Forfeit:
+		return "Rules infraction"
+	case Abandoned:
+		return "Abandoned"
+	}
+	return "Normal"
+}
+
+// Result returns the game's outcome and the method in which
+// the outcome occurred.
+func (g *Game) Result() (Outcome, Method) {
+	return g.outcome, g.method
+}
+
+// ResultString returns the outcome combined with the method such
+// as "1-0 by Checkmate".  Only the outcome is returned if there is
+// no method.
+func (g *Game) ResultString() string {
+	if g.method == NoMethod {
+		return string(g.outcome)
+	}
+	return fmt.Sprintf("%s by %s", g.outcome, g.method)
+}
+
 // FEN returns the FEN notation of the current position.
 func (g *Game) FEN() string {
 	return g.pos.String()
 }
 
+// Fingerprint returns a stable hash of the game's starting position,
+// moves, and outcome.  Tag pairs and comments are ignored so that
+// differently annotated recordings of the same game share a fingerprint.
+func (g *Game) Fingerprint() string {
+	s := g.positions[0].String() + "\n" + strings.Join(g.MovesUCI(), " ") + "\n" + string(g.outcome)
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
 // String implements the fmt.Stringer interface and returns
 // the game's PGN.
 func (g *Game) String() string {
 	return encodePGN(g)
 }
 
+// Movetext returns the game's PGN movetext without the tag pairs.
+// It includes the moves, comments, variations, and result token.
+func (g *Game) Movetext() string {
+	return encodeMovetext(g)
+}
+
+// NumberedMoves returns the moves in the game's output notation with
+// move numbers, for example "1. e4 e5 2. Nf3 Nc6", without tags,
+// comments, or a result.  If width is positive lines are wrapped so
+// that they are at most width characters long where possible.
+func (g *Game) NumberedMoves(width int) string {
+	tokens := []string{}
+	for i, m := range g.moves {
+		// keep move numbers on the same line as their move
+		pos := g.positions[i]
+		txt := g.outputNotation.Encode(pos, m)
+		if pos.turn == White {
+			txt = fmt.Sprintf("%d. %s", pos.moveCount, txt)
+		} else if i == 0 {
+			txt = fmt.Sprintf("%d... %s", pos.moveCount, txt)
+		}
+		tokens = append(tokens, txt)
+	}
+	if width <= 0 {
+		return strings.Join(tokens, " ")
+	}
+	var sb strings.Builder
+	lineLen := 0
+	for _, t := range tokens {
+		if lineLen > 0 && lineLen+1+len(t) > width {
+			sb.WriteString("\n")
+			lineLen = 0
+		} else if lineLen > 0 {
+			sb.WriteString(" ")
+			lineLen++
+		}
+		sb.WriteString(t)
+		lineLen += len(t)
+	}
+	return sb.String()
+}
+
 // MarshalText implements the encoding.TextMarshaler interface and
 // encodes the game's PGN.
 func (g *Game) MarshalText() (text []byte, err error) {
@@ -264,10 +622,13 @@ func (g *Game) UnmarshalText(text []byte) error {
 // method is valid, then the game is updated to a draw by that
 // method.  If the method isn't valid then an error is returned.
 func (g *Game) Draw(method Method) error {
+	if err := g.ensureInProgress(); err != nil {
+		return err
+	}
 	switch method {
 	case ThreefoldRepetition:
-		if g.numOfRepetitions() < 3 {
-			return errors.New("chess: draw by ThreefoldRepetition requires at least three repetitions of the current board state")
+		if !g.HasThreefoldRepetition() {
+			return errors.New("chess: draw by ThreefoldRepetition requires the current board state to have occurred at least three times")
 		}
 	case FiftyMoveRule:
 		if g.pos.halfMoveClock < 100 {
@@ -283,10 +644,14 @@ func (g *Game) Draw(method Method) error {
 }
 
 // Resign resigns the game for the given color.  If the game has
-// already been completed then the game is not updated.
-func (g *Game) Resign(color Color) {
-	if g.outcome != NoOutcome || color == NoColor {
-		return
+// already been completed then the game is not updated and
+// ErrGameOver is returned.
+func (g *Game) Resign(color Color) error {
+	if err := g.ensureInProgress(); err != nil {
+		return err
+	}
+	if color == NoColor {
+		return fmt.Errorf("chess: invalid resigning color %s", color)
 	}
 	if color == White {
 		g.outcome = BlackWon
@@ -294,12 +659,53 @@ func (g *Game) Resign(color Color) {
 		g.outcome = WhiteWon
 	}
 	g.method = Resignation
+	return nil
+}
+
+// Forfeit ends the game in favor of the opponent of the given color
+// because the color abandoned the game.  If the game has already been
+// completed then the game is not updated and ErrGameOver is returned.
+func (g *Game) Forfeit(color Color) error {
+	if err := g.ensureInProgress(); err != nil {
+		return err
+	}
+	if color == NoColor {
+		return fmt.Errorf("chess: invalid forfeiting color %s", color)
+	}
+	g.forfeit(color, Abandoned)
+	return nil
+}
+
+// forfeit ends the game by the given method in favor of the opponent
+// of the given color.
+func (g *Game) forfeit(color Color, method Method) {
+	g.outcome = WhiteWon
+	if color == White {
+		g.outcome = BlackWon
+	}
+	g.method = method
+}
+
+// ensureInProgress returns ErrGameOver if the game has an outcome.
+func (g *Game) ensureInProgress() error {
+	if g.outcome != NoOutcome {
+		return ErrGameOver
+	}
+	return nil
+}
+
+// HasThreefoldRepetition returns true if the current position has
+// occurred at least three times in the game, counting the current
+// occurrence.  Positions are the same if their RepetitionKey values
+// are equal.
+func (g *Game) HasThreefoldRepetition() bool {
+	return g.numOfRepetitions() >= 3
 }
 
 // EligibleDraws returns valid inputs for the Draw() method.
 func (g *Game) EligibleDraws() []Method {
 	draws := []Method{DrawOffer}
-	if g.numOfRepetitions() >= 3 {
+	if g.HasThreefoldRepetition() {
 		draws = append(draws, ThreefoldRepetition)
 	}
 	if g.pos.halfMoveClock >= 100 {
@@ -308,6 +714,91 @@ func (g *Game) EligibleDraws() []Method {
 	return draws
 }
 
+// Audit replays the game's moves from its initial position and
+// returns an error if any stored position or the current position
+// doesn't match the recomputed one.  It is a self-consistency check
+// for long lived games.
+func (g *Game) Audit() error {
+	if len(g.positions) != len(g.moves)+1 {
+		return fmt.Errorf("chess: game has %d positions for %d moves", len(g.positions), len(g.moves))
+	}
+	pos := g.positions[0]
+	for i, m := range g.moves {
+		if moveSlice(pos.validMovesRef()).find(m) == nil {
+			return fmt.Errorf("chess: move %s at ply %d is invalid in position %s", m, i+1, pos)
+		}
+		pos = pos.Update(m)
+		if pos.String() != g.positions[i+1].String() {
+			return fmt.Errorf("chess: position at ply %d is %s but replaying gives %s", i+1, g.positions[i+1], pos)
+		}
+	}
+	if g.pos.String() != pos.String() {
+		return fmt.Errorf("chess: current position is %s but replaying gives %s", g.pos, pos)
+	}
+	return nil
+}
+
+// Merge combines the annotations of another game with the same
+// moves into the game.  Comments, NAGs, and variations for each ply
+// are unioned with the other game's appended after the game's own.
+// An error is returned if the games' moves differ.
+func (g *Game) Merge(other *Game) error {
+	if len(g.moves) != len(other.moves) {
+		return fmt.Errorf("chess: can't merge games with %d and %d moves", len(g.moves), len(other.moves))
+	}
+	for i, m := range g.moves {
+		o := other.moves[i]
+		if m.s1 != o.s1 || m.s2 != o.s2 || m.promo != o.promo {
+			return fmt.Errorf("chess: can't merge games with different moves %s and %s at ply %d", m, o, i+1)
+		}
+	}
+	g.comments = mergeAnnotations(g.comments, other.comments, len(g.moves))
+	g.variations = mergeAnnotations(g.variations, other.variations, len(g.moves))
+	g.nags = mergeNAGs(g.nags, other.nags, len(g.moves))
+	return nil
+}
+
+// mergeAnnotations unions the per ply annotations of a and b
+// skipping duplicates.
+func mergeAnnotations(a, b [][]string, plies int) [][]string {
+	merged := make([][]string, plies)
+	for i := 0; i < plies; i++ {
+		seen := map[string]bool{}
+		for _, annotations := range [][][]string{a, b} {
+			if i >= len(annotations) {
+				continue
+			}
+			for _, s := range annotations[i] {
+				if !seen[s] {
+					seen[s] = true
+					merged[i] = append(merged[i], s)
+				}
+			}
+		}
+	}
+	return merged
+}
+
+// mergeNAGs unions the per ply NAGs of a and b skipping duplicates.
+func mergeNAGs(a, b [][]int, plies int) [][]int {
+	merged := make([][]int, plies)
+	for i := 0; i < plies; i++ {
+		seen := map[int]bool{}
+		for _, nags := range [][][]int{a, b} {
+			if i >= len(nags) {
+				continue
+			}
+			for _, nag := range nags[i] {
+				if !seen[nag] {
+					seen[nag] = true
+					merged[i] = append(merged[i], nag)
+				}
+			}
+		}
+	}
+	return merged
+}
+
 // AddTagPair adds or updates a tag pair with the given key and
 // value and returns true if the value is overwritten.
 func (g *Game) AddTagPair(k, v string) bool {
@@ -349,13 +840,19 @@ func (g *Game) RemoveTagPair(k string) bool {
 }
 
 // MoveHistory is a move's result from Game's MoveHistory method.
-// It contains the move itself, any comments, and the pre and post
-// positions.
+// It contains the move itself, any comments and numeric annotation
+// glyphs (NAGs), the pre and post positions, and details useful for
+// rendering the move.
 type MoveHistory struct {
 	PrePosition  *Position
 	PostPosition *Position
 	Move         *Move
 	Comments     []string
+	NAGs         []int
+	IsCapture    bool
+	IsCheck      bool
+	IsCheckmate  bool
+	SAN          string
 }
 
 // MoveHistory returns the moves in order along with the pre and post
@@ -367,21 +864,100 @@ func (g *Game) MoveHistory() []*MoveHistory {
 			continue
 		}
 		m := g.moves[i-1]
-		c := g.comments[i-1]
 		mh := &MoveHistory{
 			PrePosition:  g.positions[i-1],
 			PostPosition: p,
 			Move:         m,
-			Comments:     c,
+			IsCapture:    m.HasTag(Capture),
+			IsCheck:      m.HasTag(Check),
+			IsCheckmate:  p.Status() == Checkmate,
+			SAN:          AlgebraicNotation{}.Encode(g.positions[i-1], m),
+		}
+		if len(g.comments) >= i {
+			mh.Comments = g.comments[i-1]
+		}
+		if len(g.nags) >= i {
+			mh.NAGs = g.nags[i-1]
 		}
 		h = append(h, mh)
 	}
 	return h
 }
 
+// MovesByQuality returns the history of the moves annotated with the
+// given NAG, for example 4 for blunders.  Suffix annotations such as
+// "??" are parsed as their equivalent NAGs.
+func (g *Game) MovesByQuality(nag int) []*MoveHistory {
+	h := []*MoveHistory{}
+	for _, mh := range g.MoveHistory() {
+		for _, n := range mh.NAGs {
+			if n == nag {
+				h = append(h, mh)
+				break
+			}
+		}
+	}
+	return h
+}
+
+// GameView is an immutable snapshot of a game returned from Game's
+// Snapshot method.  Unlike Game, a GameView is safe for concurrent use.
+type GameView struct {
+	fen     string
+	pgn     string
+	moves   []*Move
+	outcome Outcome
+	method  Method
+}
+
+// Snapshot returns a read only view of the game's current state.
+// The view isn't affected by later changes to the game so it can be
+// shared with other goroutines, for example spectators of a live game.
+// Snapshot itself must not be called concurrently with methods that
+// modify the game.
+func (g *Game) Snapshot() *GameView {
+	return &GameView{
+		fen:     g.FEN(),
+		pgn:     g.String(),
+		moves:   g.Moves(),
+		outcome: g.outcome,
+		method:  g.method,
+	}
+}
+
+// FEN returns the FEN notation of the position at the time of the snapshot.
+func (v *GameView) FEN() string {
+	return v.fen
+}
+
+// String implements the fmt.Stringer interface and returns
+// the game's PGN at the time of the snapshot.
+func (v *GameView) String() string {
+	return v.pgn
+}
+
+// Moves returns the move history at the time of the snapshot.
+func (v *GameView) Moves() []*Move {
+	return append([]*Move(nil), v.moves...)
+}
+
+// Outcome returns the game outcome at the time of the snapshot.
+func (v *GameView) Outcome() Outcome {
+	return v.outcome
+}
+
+// Method returns the method in which the outcome occurred at the
+// time of the snapshot.
+func (v *GameView) Method() Method {
+	return v.method
+}
+
 func (g *Game) updatePosition() {
 	method := g.pos.Status()
-	if method == Stalemate {
+	if method == VariantEnd {
+		g.method = VariantEnd
+		g.outcome = variantOutcome(g.pos)
+	} else if method == Stalemate {
 		g.method = Stalemate
 		g.outcome = Draw
 	} else if method == Checkmate {
@@ -412,12 +988,31 @@ func (g *Game) updatePosition() {
 	}
 
 	// insufficient material creates automatic draw
-	if !g.ignoreAutomaticDraws && !g.pos.board.hasSufficientMaterial() {
+	if !g.ignoreAutomaticDraws && g.pos.variant == Standard && !g.pos.board.hasSufficientMaterial() {
 		g.outcome = Draw
 		g.method = InsufficientMaterial
 	}
 }
 
+func (g *Game) truncate(ply int) {
+	g.moves = g.moves[:ply]
+	g.positions = g.positions[:ply+1]
+	if len(g.comments) > ply {
+		g.comments = g.comments[:ply]
+	}
+	if len(g.variations) > ply {
+		g.variations = g.variations[:ply]
+	}
+	if len(g.nags) > ply {
+		g.nags = g.nags[:ply]
+	}
+	if ply < g.maxPlies {
+		g.maxPliesReached = false
+	}
+	g.pos = g.positions[ply]
+	g.updatePosition()
+}
+
 func (g *Game) copy(game *Game) {
 	g.tagPairs = game.TagPairs()
 	g.moves = game.Moves()
@@ -425,24 +1020,46 @@ func (g *Game) copy(game *Game) {
 	g.pos = game.pos
 	g.outcome = game.outcome
 	g.method = game.method
+	if g.discardComments {
+		return
+	}
 	g.comments = game.Comments()
+	g.variations = append([][]string(nil), game.variations...)
+	g.nags = append([][]int(nil), game.nags...)
 }
 
+// Clone returns a copy of the game including its annotations and
+// options.  Moves and positions are shared since they are immutable.
 func (g *Game) Clone() *Game {
 	return &Game{
-		tagPairs:  g.TagPairs(),
-		notation:  g.notation,
-		moves:     g.Moves(),
-		positions: g.Positions(),
-		pos:       g.pos,
-		outcome:   g.outcome,
-		method:    g.method,
+		tagPairs:             g.TagPairs(),
+		inputNotation:        g.inputNotation,
+		outputNotation:       g.outputNotation,
+		moves:                g.Moves(),
+		comments:             g.Comments(),
+		variations:           g.Variations(),
+		nags:                 append([][]int(nil), g.nags...),
+		positions:            g.Positions(),
+		pos:                  g.pos,
+		outcome:              g.outcome,
+		method:               g.method,
+		ignoreAutomaticDraws: g.ignoreAutomaticDraws,
+		discardComments:      g.discardComments,
+		defaultPromotion:     g.defaultPromotion,
+		maxPlies:             g.maxPlies,
+		onMaxPlies:           g.onMaxPlies,
+		maxPliesReached:      g.maxPliesReached,
 	}
 }
 
+// numOfRepetitions returns the number of times the current position
+// has occurred on the board including the current occurrence.  A
+// count of three means the position appears for the third time as
+// required by FIDE for a threefold repetition claim.  The initial
+// position, including one loaded from FEN, is counted as well.
 func (g *Game) numOfRepetitions() int {
 	count := 0
-	for _, pos := range g.Positions() {
+	for _, pos := range g.positions {
 		if g.pos.samePosition(pos) {
 			count++
 		}
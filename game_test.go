@@ -3,6 +3,8 @@ package chess
 import (
 	"log"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 )
 
@@ -57,6 +59,33 @@ func TestCheckmateFromFen(t *testing.T) {
 	}
 }
 
+func TestCheckmatedFromFen(t *testing.T) {
+	fenStr := "6k1/8/8/8/8/8/5PPP/3r2K1 w - - 0 1"
+	fen, err := FEN(fenStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGame(fen)
+	if g.Method() != Checkmate {
+		t.Fatalf("expected method %s but got %s", Checkmate, g.Method())
+	}
+	if g.Outcome() != BlackWon {
+		t.Fatalf("expected outcome %s but got %s", BlackWon, g.Outcome())
+	}
+}
+
+func TestFENKingRequirement(t *testing.T) {
+	fens := []string{
+		"4k3/8/8/8/8/8/8/8 w - - 0 1",
+		"3kk3/8/8/8/8/8/8/4K3 w - - 0 1",
+	}
+	for _, f := range fens {
+		if _, err := FEN(f); err == nil {
+			t.Fatalf("expected %s to be rejected for its kings", f)
+		}
+	}
+}
+
 func TestStalemate(t *testing.T) {
 	fenStr := "k1K5/8/8/8/8/8/8/1Q6 w - - 0 1"
 	fen, err := FEN(fenStr)
@@ -91,6 +120,233 @@ func TestInvalidStalemate(t *testing.T) {
 	}
 }
 
+func TestResult(t *testing.T) {
+	g := NewGame()
+	for _, m := range []string{"f3", "e6", "g4", "Qh4#"} {
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	outcome, method := g.Result()
+	if outcome != BlackWon || method != Checkmate {
+		t.Fatalf("expected 0-1 by checkmate but got %s by %s", outcome, method)
+	}
+	if s := g.ResultString(); s != "0-1 by Checkmate" {
+		t.Fatalf("expected result string 0-1 by Checkmate but got %s", s)
+	}
+	if s := NewGame().ResultString(); s != "*" {
+		t.Fatalf("expected result string * but got %s", s)
+	}
+}
+
+func TestAudit(t *testing.T) {
+	g := RandomGame(7, 60)
+	if err := g.Audit(); err != nil {
+		t.Fatal(err)
+	}
+	corrupt := g.Clone()
+	corrupt.positions[3] = corrupt.positions[5]
+	if err := corrupt.Audit(); err == nil {
+		t.Fatal("expected corrupted clone to fail the audit")
+	}
+	if err := g.Audit(); err != nil {
+		t.Fatalf("expected original game to be unaffected but got %s", err)
+	}
+}
+
+func TestGameClone(t *testing.T) {
+	opt, err := PGN(strings.NewReader("1. e4 $1 { best by test } e5 (1... c5) *"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exceeded := 0
+	g := NewGame(opt, DefaultPromotion(Queen), MaxPlies(3, func(*Game) { exceeded++ }))
+	clone := g.Clone()
+	if clone.String() != g.String() {
+		t.Fatalf("expected clone to export %s but got %s", g, clone)
+	}
+	if err := clone.MoveStr("Nf3"); err != nil {
+		t.Fatal(err)
+	}
+	if exceeded != 1 {
+		t.Fatalf("expected the clone to keep the max plies callback but it was called %d times", exceeded)
+	}
+	if err := clone.MoveStr("Nc6"); err == nil {
+		t.Fatal("expected the clone to keep the max plies limit")
+	}
+	if clone.defaultPromotion != Queen {
+		t.Fatalf("expected the clone to keep the default promotion but got %s", clone.defaultPromotion)
+	}
+	if len(g.Moves()) != 2 {
+		t.Fatalf("expected the original game to be unaffected but it has %d moves", len(g.Moves()))
+	}
+	discarded := NewGame(DiscardComments()).Clone()
+	if !discarded.discardComments {
+		t.Fatal("expected the clone to keep discarding comments")
+	}
+}
+
+func TestIsPromotionMove(t *testing.T) {
+	fen, err := FEN("k7/4P3/8/8/8/8/8/4K3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGame(fen)
+	if !g.IsPromotionMove(E7, E8) {
+		t.Fatal("expected e7e8 to be a promotion")
+	}
+	if g.IsPromotionMove(E1, E2) {
+		t.Fatal("expected king move not to be a promotion")
+	}
+	m, err := UCINotation{}.Decode(nil, "e7e8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Move(m); err == nil {
+		t.Fatal("expected move without promotion to be invalid")
+	}
+	if err := g.Move(m.WithPromo(Rook)); err != nil {
+		t.Fatal(err)
+	}
+	if p := g.Position().Board().Piece(E8); p != WhiteRook {
+		t.Fatalf("expected white rook on e8 but got %s", p)
+	}
+}
+
+func TestMaxPlies(t *testing.T) {
+	calls := 0
+	g := NewGame(MaxPlies(10, func(g *Game) {
+		calls++
+		if len(g.Moves()) != 10 {
+			t.Fatalf("expected callback after 10 moves but got %d", len(g.Moves()))
+		}
+	}))
+	moves := []string{
+		"Nf3", "Nf6", "Ng1", "Ng8",
+		"Nc3", "Nc6", "Nb1", "Nb8",
+		"e4", "e5",
+	}
+	for _, m := range moves {
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("expected callback to be called once but got %d", calls)
+	}
+	if err := g.MoveStr("Nf3"); err == nil {
+		t.Fatal("expected move after the limit to fail")
+	}
+	if calls != 1 || len(g.Moves()) != 10 {
+		t.Fatalf("expected a single callback and 10 moves but got %d and %d", calls, len(g.Moves()))
+	}
+	// undoing below the limit lets the callback fire again
+	if err := g.UnMove(); err != nil {
+		t.Fatal(err)
+	}
+	if g.maxPliesReached {
+		t.Fatal("expected the limit to be cleared after UnMove")
+	}
+	if err := g.MoveStr("e5"); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected callback to be called again after UnMove but got %d calls", calls)
+	}
+}
+
+func TestDefaultPromotion(t *testing.T) {
+	fen, err := FEN("k7/4P3/8/8/8/8/8/4K3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := NewGame(fen).MoveStr("e8"); err == nil {
+		t.Fatal("expected promotion without a piece to be rejected by default")
+	}
+	for _, test := range []struct {
+		notation Notation
+		move     string
+	}{
+		{AlgebraicNotation{}, "e8"},
+		{AlgebraicNotation{}, "e8+"},
+		{UCINotation{}, "e7e8"},
+		{LongAlgebraicNotation{}, "e7e8"},
+	} {
+		g := NewGame(fen, UseNotation(test.notation), DefaultPromotion(Knight))
+		if err := g.MoveStr(test.move); err != nil {
+			t.Fatalf("expected %s to promote but got %s", test.move, err)
+		}
+		if p := g.Position().Board().Piece(E8); p != WhiteKnight {
+			t.Fatalf("expected %s to promote to a knight but got %s", test.move, p)
+		}
+	}
+}
+
+func TestMoveSquares(t *testing.T) {
+	g := NewGame()
+	if err := g.MoveSquares(E2, E4, Queen); err != nil {
+		t.Fatal(err)
+	}
+	if p := g.Position().Board().Piece(E4); p != WhitePawn {
+		t.Fatalf("expected e4 to be a white pawn but got %s", p)
+	}
+	if err := g.MoveSquares(E7, E4, NoPieceType); err == nil {
+		t.Fatal("expected error moving e7 to e4")
+	}
+	fen, err := FEN("k7/4P3/8/8/8/8/8/4K3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g = NewGame(fen)
+	if err := g.MoveSquares(E7, E8, NoPieceType); err == nil {
+		t.Fatal("expected error promoting without a piece type")
+	}
+	if err := g.MoveSquares(E7, E8, Queen); err != nil {
+		t.Fatal(err)
+	}
+	if p := g.Position().Board().Piece(E8); p != WhiteQueen {
+		t.Fatalf("expected e8 to be a white queen but got %s", p)
+	}
+}
+
+func TestGameOverMutators(t *testing.T) {
+	g := NewGame()
+	if err := g.Resign(White); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Resign(Black); err != ErrGameOver {
+		t.Fatalf("expected resign to return ErrGameOver but got %v", err)
+	}
+	if err := g.Draw(DrawOffer); err != ErrGameOver {
+		t.Fatalf("expected draw to return ErrGameOver but got %v", err)
+	}
+	if err := g.MoveStr("e4"); err != ErrGameOver {
+		t.Fatalf("expected move to return ErrGameOver but got %v", err)
+	}
+	if g.Outcome() != BlackWon || g.Method() != Resignation {
+		t.Fatalf("expected game to remain a resignation but got %s by %s", g.Outcome(), g.Method())
+	}
+}
+
+func TestForfeit(t *testing.T) {
+	g := NewGame()
+	if err := g.MoveStr("e4"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Forfeit(White); err != nil {
+		t.Fatal(err)
+	}
+	if g.Outcome() != BlackWon || g.Method() != Abandoned {
+		t.Fatalf("expected black to win by abandonment but got %s by %s", g.Outcome(), g.Method())
+	}
+	if !strings.Contains(g.String(), `[Termination "Abandoned"]`) {
+		t.Fatalf("expected an Abandoned termination tag in %s", g.String())
+	}
+	if err := g.Forfeit(Black); err != ErrGameOver {
+		t.Fatalf("expected forfeit to return ErrGameOver but got %v", err)
+	}
+}
+
 func TestThreeFoldRepetition(t *testing.T) {
 	g := NewGame()
 	moves := []string{
@@ -125,6 +381,61 @@ func TestInvalidThreeFoldRepetition(t *testing.T) {
 	}
 }
 
+func TestThreeFoldRepetitionThirdOccurrence(t *testing.T) {
+	g := NewGame()
+	moves := []string{
+		"Nf3", "Nf6", "Ng1", "Ng8",
+		"Nf3", "Nf6", "Ng1",
+	}
+	for _, m := range moves {
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the current position has only occurred twice
+	if g.HasThreefoldRepetition() || g.numOfRepetitions() != 2 {
+		t.Fatalf("expected two occurrences but got %d", g.numOfRepetitions())
+	}
+	for _, m := range g.EligibleDraws() {
+		if m == ThreefoldRepetition {
+			t.Fatal("expected threefold repetition to be ineligible on the second occurrence")
+		}
+	}
+	if err := g.MoveStr("Ng8"); err != nil {
+		t.Fatal(err)
+	}
+	// the starting position now appears for the third time
+	if !g.HasThreefoldRepetition() || g.numOfRepetitions() != 3 {
+		t.Fatalf("expected three occurrences but got %d", g.numOfRepetitions())
+	}
+	if err := g.Draw(ThreefoldRepetition); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestThreeFoldRepetitionFromFEN(t *testing.T) {
+	fen, err := FEN("4k3/8/8/8/8/8/8/4K2R w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGame(fen)
+	moves := []string{
+		"Rh2", "Kd7", "Rh1", "Ke8",
+		"Rh2", "Kd7", "Rh1", "Ke8",
+	}
+	for _, m := range moves {
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !g.HasThreefoldRepetition() {
+		t.Fatalf("expected the fen root to count toward repetitions but got %d", g.numOfRepetitions())
+	}
+	if err := g.Draw(ThreefoldRepetition); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestFiveFoldRepetition(t *testing.T) {
 	g := NewGame()
 	moves := []string{
@@ -213,6 +524,29 @@ func TestSufficientMaterial(t *testing.T) {
 	}
 }
 
+func TestBishopsSquareColors(t *testing.T) {
+	tests := []struct {
+		fen  string
+		dead bool
+	}{
+		// dark squared bishops on c1 and h6
+		{"8/2k5/7b/8/8/3K4/8/2B5 w - - 1 1", true},
+		// light squared bishops on f1 and a6
+		{"8/2k5/b7/8/8/3K4/8/5B2 w - - 1 1", true},
+		// dark squared c1 and light squared c8
+		{"2b5/2k5/8/8/8/3K4/8/2B5 w - - 1 1", false},
+		// two white bishops on opposite colors against a lone bishop
+		{"8/2k5/7b/8/8/3K4/8/2B2B2 w - - 1 1", false},
+	}
+	for _, test := range tests {
+		pos := unsafeFEN(test.fen)
+		dead := !pos.board.hasSufficientMaterial()
+		if dead != test.dead {
+			t.Fatalf("expected %s insufficient material to be %t", test.fen, test.dead)
+		}
+	}
+}
+
 func TestSerializationCycle(t *testing.T) {
 	g := NewGame()
 	g.MoveStr("e4")
@@ -265,6 +599,39 @@ func TestPositionHash(t *testing.T) {
 	}
 }
 
+func TestPositionHashIgnoresCounters(t *testing.T) {
+	pos1 := unsafeFEN("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3")
+	pos2 := unsafeFEN("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 0 7")
+	if pos1.Hash() != pos2.Hash() {
+		t.Fatal("expected positions differing only in move counters to hash equal")
+	}
+	pos3 := unsafeFEN("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w Kkq - 2 3")
+	if pos1.Hash() == pos3.Hash() {
+		t.Fatal("expected positions with different castling rights to hash differently")
+	}
+	// the en passant square only counts when a capture is possible
+	g1 := NewGame()
+	for _, s := range []string{"Nf3", "Nf6", "e4"} {
+		if err := g1.MoveStr(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	g2 := NewGame()
+	for _, s := range []string{"e4", "Nf6", "Nf3"} {
+		if err := g2.MoveStr(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !g1.Position().SameGamePosition(g2.Position()) || g1.Position().Hash() != g2.Position().Hash() {
+		t.Fatal("expected transposition through a pawn double push to hash equal")
+	}
+	pos4 := unsafeFEN("rnbqkbnr/ppp1pppp/8/8/3pP3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 3")
+	pos5 := unsafeFEN("rnbqkbnr/ppp1pppp/8/8/3pP3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 3")
+	if pos4.Hash() == pos5.Hash() {
+		t.Fatal("expected a valid en passant square to change the hash")
+	}
+}
+
 func TestMoveHistory(t *testing.T) {
 	lens := []int{89, 89, 5, 26}
 	for i, test := range validPGNs[0:4] {
@@ -280,6 +647,341 @@ func TestMoveHistory(t *testing.T) {
 	}
 }
 
+func TestMoveHistoryDetails(t *testing.T) {
+	g := NewGame()
+	for _, m := range []string{"e4", "e5", "Bc4", "Nc6", "Qh5", "Nf6", "Qxf7#"} {
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := g.MoveHistory()
+	last := h[len(h)-1]
+	if !last.IsCapture || !last.IsCheck || !last.IsCheckmate || last.SAN != "Qxf7#" {
+		t.Fatalf("unexpected details for last move %+v", last)
+	}
+	first := h[0]
+	if first.IsCapture || first.IsCheck || first.IsCheckmate || first.SAN != "e4" {
+		t.Fatalf("unexpected details for first move %+v", first)
+	}
+}
+
+func TestPlyCount(t *testing.T) {
+	g := NewGame()
+	for _, m := range []string{"e4", "e5", "Nf3"} {
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if g.PlyCount() != 3 {
+		t.Fatalf("expected ply count %d but got %d", 3, g.PlyCount())
+	}
+	if g.Position().MoveCount() != 2 {
+		t.Fatalf("expected move count %d but got %d", 2, g.Position().MoveCount())
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	g1 := NewGame()
+	g2 := NewGame()
+	g3 := NewGame()
+	for _, m := range []string{"e4", "e5", "Nf3"} {
+		g1.MoveStr(m)
+		g2.MoveStr(m)
+	}
+	g2.AddTagPair("Event", "Casual")
+	g2.comments = [][]string{{"best by test"}, {}, {}}
+	for _, m := range []string{"e4", "e5", "Nc3"} {
+		g3.MoveStr(m)
+	}
+	if g1.Fingerprint() != g2.Fingerprint() {
+		t.Fatalf("expected fingerprints to be equal but got %s and %s", g1.Fingerprint(), g2.Fingerprint())
+	}
+	if g1.Fingerprint() == g3.Fingerprint() {
+		t.Fatalf("expected fingerprints to differ but both were %s", g1.Fingerprint())
+	}
+}
+
+func TestForEachPosition(t *testing.T) {
+	g := NewGame()
+	for _, m := range []string{"e4", "f5", "Qh5+", "g6", "Qxg6+"} {
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checks := 0
+	g.ForEachPosition(func(ply int, pos *Position) bool {
+		if pos.inCheck {
+			checks++
+		}
+		return true
+	})
+	if checks != 2 {
+		t.Fatalf("expected %d positions in check but got %d", 2, checks)
+	}
+	visited := 0
+	g.ForEachPosition(func(ply int, pos *Position) bool {
+		visited++
+		return ply < 2
+	})
+	if visited != 3 {
+		t.Fatalf("expected iteration to stop after %d positions but visited %d", 3, visited)
+	}
+}
+
+func TestSeparateInputOutputNotation(t *testing.T) {
+	g := NewGame(UseInputNotation(UCINotation{}), UseOutputNotation(AlgebraicNotation{}))
+	for _, m := range []string{"e2e4", "e7e5", "g1f3"} {
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := g.MoveStr("Nc6"); err == nil {
+		t.Fatal("expected algebraic input to be rejected")
+	}
+	expected := "\n1. e4 e5 2. Nf3 *"
+	if g.String() != expected {
+		t.Fatalf("expected pgn %q but got %q", expected, g.String())
+	}
+}
+
+func TestSwitchNotationAfterImport(t *testing.T) {
+	opt, err := PGN(strings.NewReader("1. e4 e5 (1... c5 2. Nf3) 2. Nf3 { Developing } *"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGame(opt)
+	UseNotation(UCINotation{})(g)
+	expected := "1. e2e4 e7e5 (1... c7c5 2. g1f3) 2. g1f3 { Developing } *"
+	if s := strings.Join(strings.Fields(g.String()), " "); s != expected {
+		t.Fatalf("expected movetext %q but got %q", expected, s)
+	}
+	UseNotation(AlgebraicNotation{})(g)
+	expected = "1. e4 e5 (1... c5 2. Nf3) 2. Nf3 { Developing } *"
+	if s := strings.Join(strings.Fields(g.String()), " "); s != expected {
+		t.Fatalf("expected movetext %q but got %q", expected, s)
+	}
+	opt, err = PGN(strings.NewReader("1. e4 f6 2. d4 g5 (2... e5 3. Qh5+) 3. Qh5# *"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g = NewGame(opt, UseNotation(AlgebraicNotation{NoCheckSuffix: true}))
+	expected = "1. e4 f6 2. d4 g5 (2... e5 3. Qh5) 3. Qh5 *"
+	if s := strings.Join(strings.Fields(g.Movetext()), " "); s != expected {
+		t.Fatalf("expected movetext %q but got %q", expected, s)
+	}
+}
+
+func TestFENRoundTripBlackToMove(t *testing.T) {
+	for _, fenStr := range []string{
+		"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1",
+		"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 17 42",
+		"5k2/ppp5/4P3/3R3p/6P1/1K2Nr2/PP3P2/8 b - - 1 32",
+	} {
+		fen, err := FEN(fenStr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g := NewGame(fen)
+		if g.FEN() != fenStr {
+			t.Fatalf("expected fen %s but got %s", fenStr, g.FEN())
+		}
+		if g.Clone().FEN() != fenStr {
+			t.Fatalf("expected cloned fen %s but got %s", fenStr, g.Clone().FEN())
+		}
+	}
+}
+
+func TestPositionHashes(t *testing.T) {
+	g1 := NewGame()
+	for _, s := range []string{"Nf3", "Nf6", "Nc3", "Nc6", "e4"} {
+		if err := g1.MoveStr(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	g2 := NewGame()
+	for _, s := range []string{"e4", "Nc6", "Nf3", "Nf6", "Nc3"} {
+		if err := g2.MoveStr(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h1 := g1.PositionHashes()
+	h2 := g2.PositionHashes()
+	if len(h1) != 6 || len(h2) != 6 {
+		t.Fatalf("expected %d hashes but got %d and %d", 6, len(h1), len(h2))
+	}
+	if h1[0] != h2[0] {
+		t.Fatal("expected starting position hashes to be equal")
+	}
+	if h1[1] == h2[1] {
+		t.Fatal("expected different positions to have different hashes")
+	}
+	// the double push leaves an en passant square without a capture
+	if h1[5] != h2[5] {
+		t.Fatalf("expected transposed positions to share a hash but got %d and %d", h1[5], h2[5])
+	}
+}
+
+func TestRewindTo(t *testing.T) {
+	g := NewGame()
+	for _, m := range []string{"e4", "e5", "Nf3", "Nc6", "Bb5"} {
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	target := g.Positions()[3]
+	if err := g.RewindTo(target); err != nil {
+		t.Fatal(err)
+	}
+	if g.PlyCount() != 3 {
+		t.Fatalf("expected ply count %d but got %d", 3, g.PlyCount())
+	}
+	if g.FEN() != target.String() {
+		t.Fatalf("expected fen %s but got %s", target, g.FEN())
+	}
+	if err := g.RewindTo(unsafeFEN("4k3/8/8/8/8/8/8/4K3 w - - 0 1")); err == nil {
+		t.Fatal("expected error rewinding to a position not in the game")
+	}
+}
+
+func TestTruncateTo(t *testing.T) {
+	g, err := decodePGN(`1. e4 e5 2. Nf3 Nc6 3. Bb5 a6 4. Ba4 Nf6 5. O-O Be7 { Closed } 6. Re1 b5 7. Bb3 d6 8. c3 O-O 9. h3 Nb8 10. d4 Nbd7 { Breyer } 1-0`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Moves()) != 20 {
+		t.Fatalf("expected 20 moves but got %d", len(g.Moves()))
+	}
+	if err := g.TruncateTo(8); err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Moves()) != 8 || len(g.Positions()) != 9 || len(g.Comments()) != 8 {
+		t.Fatalf("expected 8 moves, 9 positions, and 8 comment slots but got %d, %d, and %d", len(g.Moves()), len(g.Positions()), len(g.Comments()))
+	}
+	if g.Position() != g.Positions()[8] || g.Outcome() != NoOutcome {
+		t.Fatalf("expected the game to continue from ply 8 but got %s", g.Outcome())
+	}
+	if err := g.MoveStr("O-O"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.TruncateTo(10); err == nil {
+		t.Fatal("expected error truncating past the last ply")
+	}
+}
+
+func TestSnapshotConcurrency(t *testing.T) {
+	g := NewGame()
+	var latest atomic.Value
+	latest.Store(g.Snapshot())
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				v := latest.Load().(*GameView)
+				if _, err := decodeFEN(v.FEN()); err != nil {
+					t.Error(err)
+					return
+				}
+				_ = v.String()
+				_ = v.Moves()
+				_ = v.Outcome()
+			}
+		}()
+	}
+	moves := []string{"e4", "e5", "Nf3", "Nc6", "Bb5", "a6", "Ba4", "Nf6"}
+	for _, m := range moves {
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+		latest.Store(g.Snapshot())
+	}
+	close(done)
+	wg.Wait()
+	v := latest.Load().(*GameView)
+	if len(v.Moves()) != len(moves) || v.FEN() != g.FEN() {
+		t.Fatalf("expected snapshot to match game but got %s", v.FEN())
+	}
+}
+
+func TestMovesUCI(t *testing.T) {
+	g := NewGame()
+	for _, m := range []string{"e4", "e5", "Nf3", "Nc6", "Bb5", "Nf6", "O-O"} {
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := "e2e4 e7e5 g1f3 b8c6 f1b5 g8f6 e1g1"
+	actual := strings.Join(g.MovesUCI(), " ")
+	if actual != expected {
+		t.Fatalf("expected uci moves %s but got %s", expected, actual)
+	}
+}
+
+func TestChecksGiven(t *testing.T) {
+	g := NewGame()
+	for _, m := range []string{"e4", "f5", "Qh5+", "g6", "Qxg6+", "hxg6", "Bd3", "Rxh2"} {
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if white, black := g.ChecksGiven(); white != 2 || black != 0 {
+		t.Fatalf("expected 2 and 0 checks but got %d and %d", white, black)
+	}
+}
+
+func TestNumberedMoves(t *testing.T) {
+	g := NewGame()
+	for _, m := range []string{"e4", "e5", "Nf3", "Nc6", "Bb5"} {
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s := g.NumberedMoves(0); s != "1. e4 e5 2. Nf3 Nc6 3. Bb5" {
+		t.Fatalf("unexpected numbered moves %s", s)
+	}
+	if s := g.NumberedMoves(12); s != "1. e4 e5\n2. Nf3 Nc6\n3. Bb5" {
+		t.Fatalf("unexpected wrapped numbered moves %q", s)
+	}
+	fen, err := FEN("4k3/8/8/8/8/8/4P3/4K3 b - - 0 7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g = NewGame(fen)
+	g.MoveStr("Kd7")
+	g.MoveStr("e4")
+	if s := g.NumberedMoves(0); s != "7... Kd7 8. e4" {
+		t.Fatalf("unexpected numbered moves from fen %s", s)
+	}
+}
+
+func TestUCIPositionCommand(t *testing.T) {
+	g := NewGame()
+	if s := g.UCIPositionCommand(); s != "position startpos" {
+		t.Fatalf("expected position startpos but got %s", s)
+	}
+	g.MoveStr("e4")
+	g.MoveStr("e5")
+	if s := g.UCIPositionCommand(); s != "position startpos moves e2e4 e7e5" {
+		t.Fatalf("unexpected command %s", s)
+	}
+	fen, err := FEN("4k3/8/8/8/8/8/4P3/4K3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g = NewGame(fen)
+	g.MoveStr("e4")
+	if s := g.UCIPositionCommand(); s != "position fen 4k3/8/8/8/8/8/4P3/4K3 w - - 0 1 moves e2e4" {
+		t.Fatalf("unexpected command %s", s)
+	}
+}
+
 func BenchmarkStalemateStatus(b *testing.B) {
 	fenStr := "k1K5/8/8/8/8/8/8/1Q6 w - - 0 1"
 	fen, err := FEN(fenStr)
@@ -312,6 +1014,34 @@ func BenchmarkInvalidStalemateStatus(b *testing.B) {
 	}
 }
 
+// BenchmarkGameMove plays a sixteen ply opening from the starting
+// position.  Comparing repetitions by board bitboards instead of board
+// strings and avoiding copies of the valid move cache reduced it from
+// about 26,500 to about 720 allocs/op.
+func BenchmarkGameMove(b *testing.B) {
+	moves := []string{
+		"e4", "e5", "Nf3", "Nc6", "Bb5", "a6", "Ba4", "Nf6",
+		"O-O", "Be7", "Re1", "b5", "Bb3", "d6", "c3", "O-O",
+	}
+	start := NewGame()
+	for _, s := range moves {
+		if err := start.MoveStr(s); err != nil {
+			b.Fatal(err)
+		}
+	}
+	line := start.Moves()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		g := NewGame()
+		for _, m := range line {
+			if err := g.Move(m); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
 func BenchmarkPositionHash(b *testing.B) {
 	fenStr := "8/3P4/8/8/8/7k/7p/7K w - - 2 70"
 	fen, err := FEN(fenStr)
@@ -324,3 +1054,13 @@ func BenchmarkPositionHash(b *testing.B) {
 		g.Position().Hash()
 	}
 }
+
+// BenchmarkNewGame measures creating a game and playing its first
+// move.  Caching the starting position reduced it from about 172 to
+// 99 allocs/op.
+func BenchmarkNewGame(b *testing.B) {
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		NewGame().MoveStr("e4")
+	}
+}
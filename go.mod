@@ -1,5 +1,8 @@
 module github.com/krunduev/notnil-chess
 
-go 1.21.4
+go 1.22
 
-require github.com/ajstarks/svgo v0.0.0-20211024235047-1546f124cd8b
+require (
+	github.com/ajstarks/svgo v0.0.0-20211024235047-1546f124cd8b
+	github.com/klauspost/compress v1.18.0
+)
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"image/color"
 	"io"
+	"math"
 	"strings"
 
 	svg "github.com/ajstarks/svgo"
@@ -42,6 +43,26 @@ func MarkSquares(c color.Color, sqs ...chess.Square) func(*encoder) {
 	}
 }
 
+// An Arrow points from one square to another.  Arrows are
+// commonly used to show engine lines or plans in annotated
+// diagrams.
+type Arrow struct {
+	From chess.Square
+	To   chess.Square
+}
+
+// Arrows is designed to be used as an optional argument
+// to the SVG function.  It draws the given arrows on top
+// of the board in the color given.  Squares can be
+// highlighted alongside arrows with MarkSquares.
+func Arrows(c color.Color, arrows ...Arrow) func(*encoder) {
+	return func(e *encoder) {
+		for _, a := range arrows {
+			e.arrows = append(e.arrows, coloredArrow{Arrow: a, color: c})
+		}
+	}
+}
+
 // Perspective is designed to be used as an optional argument
 // to the SVG function.  It draws the board from the perspective
 // of the given color.  White is the default.
@@ -58,6 +79,12 @@ type encoder struct {
 	dark        color.Color
 	perspective chess.Color
 	marks       map[chess.Square]color.Color
+	arrows      []coloredArrow
+}
+
+type coloredArrow struct {
+	Arrow
+	color color.Color
 }
 
 // New returns an encoder that writes to the given writer.
@@ -80,6 +107,8 @@ func new(w io.Writer, options []func(*encoder)) *encoder {
 const (
 	sqWidth     = 45
 	sqHeight    = 45
+	arrowWidth  = 8
+	arrowHead   = 20
 	boardWidth  = 8 * sqWidth
 	boardHeight = 8 * sqHeight
 )
@@ -138,10 +167,49 @@ func (e *encoder) EncodeSVG(b *chess.Board) error {
 			}
 		}
 	}
+	for _, a := range e.arrows {
+		e.drawArrow(canvas, a)
+	}
 	canvas.End()
 	return nil
 }
 
+func (e *encoder) drawArrow(canvas *svg.SVG, a coloredArrow) {
+	x1, y1 := e.squareCenter(a.From)
+	x2, y2 := e.squareCenter(a.To)
+	dx, dy := x2-x1, y2-y1
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return
+	}
+	// unit vectors along and perpendicular to the arrow
+	ux, uy := dx/length, dy/length
+	px, py := -uy, ux
+	baseX := x2 - ux*arrowHead
+	baseY := y2 - uy*arrowHead
+	style := "opacity:0.8;fill: " + colorToHex(a.color)
+	canvas.Line(round(x1), round(y1), round(baseX), round(baseY), fmt.Sprintf("opacity:0.8;stroke-width:%d;stroke: %s", arrowWidth, colorToHex(a.color)))
+	xs := []int{round(x2), round(baseX + px*arrowHead/2), round(baseX - px*arrowHead/2)}
+	ys := []int{round(y2), round(baseY + py*arrowHead/2), round(baseY - py*arrowHead/2)}
+	canvas.Polygon(xs, ys, style)
+}
+
+// squareCenter returns the pixel coordinates of the center of
+// the square taking the perspective into account.
+func (e *encoder) squareCenter(sq chess.Square) (float64, float64) {
+	col := int(sq.File())
+	row := 7 - int(sq.Rank())
+	if e.perspective == chess.Black {
+		col = 7 - col
+		row = 7 - row
+	}
+	return float64(col*sqWidth) + sqWidth/2.0, float64(row*sqHeight) + sqHeight/2.0
+}
+
+func round(f float64) int {
+	return int(math.Round(f))
+}
+
 func (e *encoder) colorForSquare(sq chess.Square) color.Color {
 	sqSum := int(sq.File()) + int(sq.Rank())
 	if sqSum%2 == 0 {
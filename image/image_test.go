@@ -16,6 +16,7 @@ import (
 
 const expectedMD5 = "08aaa6fcfde3bb900fc54bdfef3d5c81"
 const expectedMD5Black = "badac5ca5cfbdea9b98a1f9988ba54bc"
+const expectedMD5Arrows = "9987ae191fe0ae8cbab2216949bc08b3"
 
 func TestSVG(t *testing.T) {
 	// create buffer of actual svg
@@ -80,3 +81,22 @@ func TestSVGFromBlack(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestSVGArrows(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{})
+	fenStr := "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+	pos := &chess.Position{}
+	if err := pos.UnmarshalText([]byte(fenStr)); err != nil {
+		t.Error(err)
+	}
+	arrows := image.Arrows(color.RGBA{0, 128, 0, 1}, image.Arrow{From: chess.E2, To: chess.E4})
+	mark := image.MarkSquares(color.RGBA{255, 255, 0, 1}, chess.D5)
+	if err := image.SVG(buf, pos.Board(), arrows, mark); err != nil {
+		t.Error(err)
+	}
+	actualSVG := strings.TrimSpace(buf.String())
+	actualMD5 := fmt.Sprintf("%x", md5.Sum([]byte(actualSVG)))
+	if actualMD5 != expectedMD5Arrows {
+		t.Errorf("expected actual md5 hash to be %s but got %s", expectedMD5Arrows, actualMD5)
+	}
+}
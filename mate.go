@@ -0,0 +1,55 @@
+package chess
+
+// MaxMateDepth is the largest depth accepted by MateIn.  Deeper
+// searches are rejected to avoid exponential blowups.
+const MaxMateDepth = 5
+
+// MateIn searches for a forced checkmate by the side to move in at
+// most n of its moves.  If one exists, the mating line is returned
+// with the first defending reply chosen at each step.  The search is
+// a bounded minimax over ValidMoves and returns false if n is less
+// than one or greater than MaxMateDepth.
+func MateIn(pos *Position, n int) ([]*Move, bool) {
+	if pos == nil || n < 1 || n > MaxMateDepth {
+		return nil, false
+	}
+	for i := 1; i <= n; i++ {
+		if line := mateIn(pos, i); line != nil {
+			return line, true
+		}
+	}
+	return nil, false
+}
+
+// mateIn returns a line mating in exactly n attacking moves or less,
+// or nil if the defender can avoid it.
+func mateIn(pos *Position, n int) []*Move {
+	for _, m := range pos.ValidMoves() {
+		next := pos.Update(m)
+		replies := next.ValidMoves()
+		if len(replies) == 0 {
+			if next.inCheck {
+				return []*Move{m}
+			}
+			continue
+		}
+		if n == 1 {
+			continue
+		}
+		var line []*Move
+		for _, reply := range replies {
+			cont := mateIn(next.Update(reply), n-1)
+			if cont == nil {
+				line = nil
+				break
+			}
+			if line == nil {
+				line = append([]*Move{m, reply}, cont...)
+			}
+		}
+		if line != nil {
+			return line
+		}
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package chess
+
+import "testing"
+
+func TestMateIn(t *testing.T) {
+	pos := unsafeFEN("k7/8/2K5/8/8/8/8/7R w - - 0 1")
+	if _, ok := MateIn(pos, 1); ok {
+		t.Fatal("expected no mate in one")
+	}
+	line, ok := MateIn(pos, 2)
+	if !ok {
+		t.Fatal("expected to find a mate in two")
+	}
+	if len(line) != 3 {
+		t.Fatalf("expected a three ply line but got %v", line)
+	}
+	for _, m := range line {
+		pos = pos.Update(m)
+	}
+	if pos.Status() != Checkmate {
+		t.Fatalf("expected line %v to end in checkmate", line)
+	}
+}
@@ -1,5 +1,11 @@
 package chess
 
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
 // A MoveTag represents a notable consequence of a move.
 type MoveTag uint16
 
@@ -33,6 +39,74 @@ func (m *Move) String() string {
 	return m.s1.String() + m.s2.String() + m.promo.String()
 }
 
+// moveTagNames are the names of the exported move tags used in the
+// move's JSON encoding.
+var moveTagNames = []struct {
+	tag  MoveTag
+	name string
+}{
+	{KingSideCastle, "kingSideCastle"},
+	{QueenSideCastle, "queenSideCastle"},
+	{Capture, "capture"},
+	{EnPassant, "enPassant"},
+	{Check, "check"},
+}
+
+type moveJSON struct {
+	From  string   `json:"from"`
+	To    string   `json:"to"`
+	Promo string   `json:"promo"`
+	Tags  []string `json:"tags,omitempty"`
+}
+
+// MarshalJSON implements the json.Marshaler interface.  The move is
+// encoded independently of any position, for example
+// {"from":"e7","to":"e8","promo":"q"}.
+func (m *Move) MarshalJSON() ([]byte, error) {
+	j := moveJSON{From: m.s1.String(), To: m.s2.String(), Promo: m.promo.String()}
+	for _, t := range moveTagNames {
+		if m.HasTag(t.tag) {
+			j.Tags = append(j.Tags, t.name)
+		}
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (m *Move) UnmarshalJSON(data []byte) error {
+	var j moveJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	s1, ok := strToSquareMap[j.From]
+	if !ok {
+		return fmt.Errorf("chess: invalid move origin %q", j.From)
+	}
+	s2, ok := strToSquareMap[j.To]
+	if !ok {
+		return fmt.Errorf("chess: invalid move destination %q", j.To)
+	}
+	promo := pieceTypeFromChar(j.Promo)
+	if j.Promo != "" && promo == NoPieceType {
+		return fmt.Errorf("chess: invalid move promotion %q", j.Promo)
+	}
+	var tags MoveTag
+	for _, name := range j.Tags {
+		found := false
+		for _, t := range moveTagNames {
+			if t.name == name {
+				tags |= t.tag
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("chess: invalid move tag %q", name)
+		}
+	}
+	*m = Move{s1: s1, s2: s2, promo: promo, tags: tags}
+	return nil
+}
+
 // S1 returns the origin square of the move.
 func (m *Move) S1() Square {
 	return m.s1
@@ -48,6 +122,15 @@ func (m *Move) Promo() PieceType {
 	return m.promo
 }
 
+// WithPromo returns a copy of the move promoting to the given piece
+// type.  It supports choosing the promotion after the origin and
+// destination squares are known.
+func (m *Move) WithPromo(pt PieceType) *Move {
+	cp := *m
+	cp.promo = pt
+	return &cp
+}
+
 // HasTag returns true if the move contains the MoveTag given.
 func (m *Move) HasTag(tag MoveTag) bool {
 	return (tag & m.tags) > 0
@@ -57,6 +140,37 @@ func (m *Move) addTag(tag MoveTag) {
 	m.tags = m.tags | tag
 }
 
+// Compare orders moves by origin square, then destination square,
+// then promotion piece type.  It returns -1 if the move sorts before
+// other, 1 if it sorts after, and 0 if they are the same move.
+func (m *Move) Compare(other *Move) int {
+	switch {
+	case m.s1 != other.s1:
+		return compareInts(int(m.s1), int(other.s1))
+	case m.s2 != other.s2:
+		return compareInts(int(m.s2), int(other.s2))
+	}
+	return compareInts(int(m.promo), int(other.promo))
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// SortMoves sorts the moves in place in the order defined by
+// Move's Compare method.
+func SortMoves(moves []*Move) {
+	sort.SliceStable(moves, func(i, j int) bool {
+		return moves[i].Compare(moves[j]) < 0
+	})
+}
+
 type moveSlice []*Move
 
 func (a moveSlice) find(m *Move) *Move {
@@ -64,7 +178,7 @@ func (a moveSlice) find(m *Move) *Move {
 		return nil
 	}
 	for _, move := range a {
-		if move.String() == m.String() {
+		if move.s1 == m.s1 && move.s2 == m.s2 && move.promo == m.promo {
 			return move
 		}
 	}
@@ -1,7 +1,10 @@
 package chess
 
 import (
+	"encoding/json"
+	"fmt"
 	"log"
+	"math/rand"
 	"testing"
 )
 
@@ -65,6 +68,9 @@ var (
 		{m: &Move{s1: E1, s2: C1}, pos: unsafeFEN("r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1")},
 		{m: &Move{s1: E8, s2: G8}, pos: unsafeFEN("r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1")},
 		{m: &Move{s1: E8, s2: C8}, pos: unsafeFEN("r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1")},
+		// queen side castle when only the rook passes an attacked square
+		{m: &Move{s1: E1, s2: C1}, pos: unsafeFEN("1r2k3/8/8/8/8/8/8/R3K2R w KQ - 0 1")},
+		{m: &Move{s1: E8, s2: C8}, pos: unsafeFEN("r3k2r/8/8/8/8/8/8/1R2K3 b kq - 0 1")},
 		// king moving in front of enemy pawn http://en.lichess.org/4HXJOtpN#75
 		{m: &Move{s1: F8, s2: G7}, pos: unsafeFEN("3rrk2/8/2p3P1/1p2nP1p/pP2p3/P1B1NbPB/2P2K2/5R2 b - - 1 38")},
 	}
@@ -110,12 +116,21 @@ var (
 		{m: &Move{s1: E1, s2: C1}, pos: unsafeFEN("3rk2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1")},
 		{m: &Move{s1: E1, s2: G1}, pos: unsafeFEN("r3k2r/8/8/8/8/8/8/R3K2R w Qkq - 0 1")},
 		{m: &Move{s1: E1, s2: C1}, pos: unsafeFEN("r3k2r/8/8/8/8/8/8/R3K2R w Kkq - 0 1")},
+		// king may not castle out of, through, or into check
+		{m: &Move{s1: E1, s2: G1}, pos: unsafeFEN("4r1k1/8/8/8/8/8/8/R3K2R w KQ - 0 1")},
+		{m: &Move{s1: E1, s2: G1}, pos: unsafeFEN("5rk1/8/8/8/8/8/8/R3K2R w KQ - 0 1")},
+		{m: &Move{s1: E1, s2: G1}, pos: unsafeFEN("6rk/8/8/8/8/8/8/R3K2R w KQ - 0 1")},
+		{m: &Move{s1: E8, s2: C8}, pos: unsafeFEN("r3k3/8/8/8/8/8/8/3RK3 b q - 0 1")},
 		// invalid promotion for non-pawn move
 		{m: &Move{s1: B8, s2: D7, promo: Pawn}, pos: unsafeFEN("rn1qkb1r/pp3ppp/2p1pn2/3p4/2PP4/2NQPN2/PP3PPP/R1B1K2R b KQkq - 0 7")},
 		// en passant on doubled pawn file http://en.lichess.org/TnRtrHxf#24
 		{m: &Move{s1: E3, s2: F6}, pos: unsafeFEN("r1b2rk1/pp2b1pp/1qn1p3/3pPp2/1P1P4/P2BPN2/6PP/RN1Q1RK1 w - f6 0 13")},
 		// can't move piece out of pin (even if checking enemy king) http://en.lichess.org/JCRBhXH7#62
 		{m: &Move{s1: E1, s2: E7}, pos: unsafeFEN("4R3/1r1k2pp/p1p5/1pP5/8/8/1PP3PP/2K1Rr2 w - - 5 32")},
+		// pinned pieces can't block a check
+		{m: &Move{s1: D2, s2: E4}, pos: unsafeFEN("4r2k/8/8/8/1b6/8/3N4/4K3 w - - 0 1")},
+		{m: &Move{s1: E3, s2: D2}, pos: unsafeFEN("4r2k/8/8/8/1b6/4B3/8/4K3 w - - 0 1")},
+		{m: &Move{s1: D7, s2: E5}, pos: unsafeFEN("4k3/3n4/8/1B6/8/8/8/4R2K b - - 0 1")},
 		// invalid one up pawn capture
 		{m: &Move{s1: E6, s2: E5}, pos: unsafeFEN(`2b1r3/2k2p1B/p2np3/4B3/8/5N2/PP1K1PPP/3R4 b - - 2 1`)},
 		// invalid two up pawn capture
@@ -222,6 +237,57 @@ func TestInvalidMoves(t *testing.T) {
 	}
 }
 
+func TestPinnedPieceCannotBlockCheck(t *testing.T) {
+	tests := []struct {
+		fen    string
+		pinned Square
+		moves  string
+	}{
+		// knight pinned by the bishop could block the rook on e4
+		{"4r2k/8/8/8/1b6/8/3N4/4K3 w - - 0 1", D2, "[e1d1 e1f1 e1f2]"},
+		// bishop pinned by the rook could block the bishop on d2
+		{"4r2k/8/8/8/1b6/4B3/8/4K3 w - - 0 1", E3, "[e1d1 e1f1 e1e2 e1f2]"},
+	}
+	for _, test := range tests {
+		pos := unsafeFEN(test.fen)
+		moves := pos.ValidMoves()
+		for _, m := range moves {
+			if m.S1() == test.pinned {
+				t.Fatalf("expected pinned piece on %s to have no moves in %s but got %s", test.pinned, test.fen, m)
+			}
+		}
+		SortMoves(moves)
+		if s := fmt.Sprint(moves); s != test.moves {
+			t.Fatalf("expected moves %s in %s but got %s", test.moves, test.fen, s)
+		}
+	}
+}
+
+func TestSortMoves(t *testing.T) {
+	pos := unsafeFEN("r3k2r/1P6/8/8/8/8/6p1/R3K2R w KQkq - 0 1")
+	sorted := pos.ValidMoves()
+	SortMoves(sorted)
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i-1].Compare(sorted[i]) >= 0 {
+			t.Fatalf("expected %s to sort before %s", sorted[i-1], sorted[i])
+		}
+	}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 10; i++ {
+		moves := pos.ValidMoves()
+		r.Shuffle(len(moves), func(i, j int) { moves[i], moves[j] = moves[j], moves[i] })
+		SortMoves(moves)
+		if fmt.Sprint(moves) != fmt.Sprint(sorted) {
+			t.Fatalf("expected sorted moves %v but got %v", sorted, moves)
+		}
+	}
+	a := &Move{s1: B7, s2: B8, promo: Queen}
+	b := &Move{s1: B7, s2: B8, promo: Knight}
+	if a.Compare(b) != -1 || b.Compare(a) != 1 || a.Compare(a) != 0 {
+		t.Fatal("expected promotions to be ordered by piece type")
+	}
+}
+
 func TestPositionUpdates(t *testing.T) {
 	for _, mt := range positionUpdates {
 		if !moveIsValid(mt.pos, mt.m, true) {
@@ -281,6 +347,19 @@ var perfResults = []perfTest{
 		46, 2079, 89890,
 		// 3894594, 164075551, 6923051137, 287188994746, 11923589843526, 490154852788714
 	}},
+	// Chess960 positions
+	{pos: unsafeFEN("bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9"), nodesPerDepth: []int{
+		21, 528, 12189,
+		// 326672, 8146062, 227689589
+	}},
+	{pos: unsafeFEN("2nnrbkr/p1qppppp/8/1ppb4/6PP/3PP3/PPP2P2/BQNNRBKR w HEhe - 1 9"), nodesPerDepth: []int{
+		21, 807, 18002,
+		// 667366
+	}},
+	{pos: unsafeFEN("b1q1rrkb/pppppppp/3nn3/8/P7/1PPP4/4PPPP/BQNNRKRB w GE - 1 9"), nodesPerDepth: []int{
+		20, 479, 10471,
+		// 273318
+	}},
 }
 
 func TestPerfResults(t *testing.T) {
@@ -289,6 +368,67 @@ func TestPerfResults(t *testing.T) {
 	}
 }
 
+func TestPerfResultsMakeUnmake(t *testing.T) {
+	for _, perf := range perfResults {
+		pos := perf.pos.copy()
+		fen := pos.String()
+		board := *pos.board
+		for depth, expected := range perf.nodesPerDepth {
+			if depth > 2 {
+				break
+			}
+			if nodes := perftMakeUnmake(pos, depth+1); nodes != expected {
+				t.Fatalf("expected %d nodes at depth %d for %s but got %d", expected, depth+1, fen, nodes)
+			}
+			if pos.String() != fen || *pos.board != board {
+				t.Fatalf("expected unmake to restore %s but got %s", fen, pos.String())
+			}
+		}
+	}
+}
+
+func perftMakeUnmake(pos *Position, depth int) int {
+	moves := pos.ValidMoves()
+	if depth == 1 {
+		return len(moves)
+	}
+	nodes := 0
+	for _, m := range moves {
+		u := pos.MakeMove(m)
+		nodes += perftMakeUnmake(pos, depth-1)
+		pos.UnmakeMove(u)
+	}
+	return nodes
+}
+
+func BenchmarkPerftMakeUnmake(b *testing.B) {
+	pos := StartingPosition()
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		perftMakeUnmake(pos, 3)
+	}
+}
+
+func BenchmarkPerftUpdate(b *testing.B) {
+	var perft func(pos *Position, depth int) int
+	perft = func(pos *Position, depth int) int {
+		moves := pos.ValidMoves()
+		if depth == 1 {
+			return len(moves)
+		}
+		nodes := 0
+		for _, m := range moves {
+			nodes += perft(pos.Update(m), depth-1)
+		}
+		return nodes
+	}
+	pos := StartingPosition()
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		perft(pos, 3)
+	}
+}
+
 func countMoves(t *testing.T, originalPosition *Position, positions []*Position, nodesPerDepth []int, maxDepth int) {
 	if len(nodesPerDepth) == 0 {
 		return
@@ -329,6 +469,79 @@ func BenchmarkValidMoves(b *testing.B) {
 	}
 }
 
+func TestValidMovesInto(t *testing.T) {
+	pos := unsafeFEN("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	buf := []*Move{nil}
+	moves := pos.ValidMovesInto(buf)
+	if len(moves) != 49 || moves[0] != nil {
+		t.Fatalf("expected 48 moves appended to the buffer but got %d", len(moves)-1)
+	}
+	if pos.validMoves != nil {
+		t.Fatal("expected moves generated into a buffer not to be cached")
+	}
+	if cached := pos.ValidMovesInto(pos.ValidMoves()[:0]); len(cached) != 48 {
+		t.Fatalf("expected 48 cached moves but got %d", len(cached))
+	}
+	buf2 := GetMoveBuffer()
+	*buf2 = pos.ValidMovesInto((*buf2)[:0])
+	if len(*buf2) != 48 {
+		t.Fatalf("expected 48 moves in the pooled buffer but got %d", len(*buf2))
+	}
+	PutMoveBuffer(buf2)
+	if len(*buf2) != 0 {
+		t.Fatalf("expected returned buffer to be empty but got %d moves", len(*buf2))
+	}
+}
+
+func BenchmarkValidMovesNewSlice(b *testing.B) {
+	positions := benchmarkPositions()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, pos := range positions {
+			engine{}.CalcMoves(pos, false)
+		}
+	}
+}
+
+func BenchmarkValidMovesInto(b *testing.B) {
+	positions := benchmarkPositions()
+	buf := make([]*Move, 0, 256)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, pos := range positions {
+			buf = pos.ValidMovesInto(buf[:0])
+		}
+	}
+}
+
+func BenchmarkValidMovesPooled(b *testing.B) {
+	positions := benchmarkPositions()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, pos := range positions {
+			buf := GetMoveBuffer()
+			*buf = pos.ValidMovesInto((*buf)[:0])
+			PutMoveBuffer(buf)
+		}
+	}
+}
+
+func benchmarkPositions() []*Position {
+	positions := []*Position{}
+	for _, fen := range []string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		"r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10",
+	} {
+		positions = append(positions, unsafeFEN(fen))
+	}
+	return positions
+}
+
 func moveIsValid(pos *Position, m *Move, useTags bool) bool {
 	for _, move := range pos.ValidMoves() {
 		if move.s1 == m.s1 && move.s2 == m.s2 && move.promo == m.promo {
@@ -342,3 +555,30 @@ func moveIsValid(pos *Position, m *Move, useTags bool) bool {
 	}
 	return false
 }
+
+func TestMoveJSON(t *testing.T) {
+	promo := &Move{s1: E7, s2: E8, promo: Queen, tags: Check}
+	b, err := json.Marshal(promo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"from":"e7","to":"e8","promo":"q","tags":["check"]}` {
+		t.Fatalf("unexpected json %s", b)
+	}
+	for _, m := range []*Move{promo, {s1: E1, s2: G1, tags: KingSideCastle}} {
+		b, err := json.Marshal(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded := &Move{}
+		if err := json.Unmarshal(b, decoded); err != nil {
+			t.Fatal(err)
+		}
+		if *decoded != *m {
+			t.Fatalf("expected %+v after round trip but got %+v", m, decoded)
+		}
+	}
+	if err := json.Unmarshal([]byte(`{"from":"e9","to":"e4","promo":""}`), &Move{}); err == nil {
+		t.Fatal("expected error decoding an invalid square")
+	}
+}
@@ -28,6 +28,30 @@ type Notation interface {
 	Decoder
 }
 
+// ValidateLine checks that the moves, encoded in the given notation,
+// can be played in order from the starting FEN without building a
+// Game.  It returns the index of the first move that can't be decoded
+// or isn't valid along with an error describing it.  If all of the
+// moves are valid -1 and a nil error are returned.
+func ValidateLine(startFEN string, moves []string, n Notation) (int, error) {
+	pos, err := decodeFEN(startFEN)
+	if err != nil {
+		return -1, err
+	}
+	for i, s := range moves {
+		m, err := n.Decode(pos, s)
+		if err != nil {
+			return i, err
+		}
+		valid := moveSlice(pos.ValidMoves()).find(m)
+		if valid == nil {
+			return i, fmt.Errorf("chess: invalid move %s at index %d for position %s", s, i, pos)
+		}
+		pos = pos.Update(valid)
+	}
+	return -1, nil
+}
+
 // UCINotation is a more computer friendly alternative to algebraic
 // notation.  This notation uses the same format as the UCI (Universal Chess
 // Interface).  Examples: e2e4, e7e5, e1g1 (white short castling), e7e8q (for promotion)
@@ -76,6 +100,14 @@ func (UCINotation) Decode(pos *Position, s string) (*Move, error) {
 			m.addTag(KingSideCastle)
 		} else if (s1 == E1 && s2 == C1) || (s1 == E8 && s2 == C8) {
 			m.addTag(QueenSideCastle)
+		} else if pos.Board().Piece(s2) == NewPiece(Rook, p.Color()) {
+			// Chess960 castles are encoded as the king moving to the
+			// square of the rook it castles with
+			if s2.File() > s1.File() {
+				m.addTag(KingSideCastle)
+			} else {
+				m.addTag(QueenSideCastle)
+			}
 		}
 	} else if p.Type() == Pawn && s2 == pos.enPassantSquare {
 		m.addTag(EnPassant)
@@ -92,7 +124,11 @@ func (UCINotation) Decode(pos *Position, s string) (*Move, error) {
 // AlgebraicNotation (or Standard Algebraic Notation) is the
 // official chess notation used by FIDE. Examples: e4, e5,
 // O-O (short castling), e8=Q (promotion)
-type AlgebraicNotation struct{}
+type AlgebraicNotation struct {
+	// NoCheckSuffix omits the check (+) and checkmate (#) suffixes
+	// when encoding.  Decoding accepts moves with or without them.
+	NoCheckSuffix bool
+}
 
 // String implements the fmt.Stringer interface and returns
 // the notation's name.
@@ -101,8 +137,11 @@ func (AlgebraicNotation) String() string {
 }
 
 // Encode implements the Encoder interface.
-func (AlgebraicNotation) Encode(pos *Position, m *Move) string {
-	checkChar := getCheckChar(pos, m)
+func (a AlgebraicNotation) Encode(pos *Position, m *Move) string {
+	checkChar := ""
+	if !a.NoCheckSuffix {
+		checkChar = getCheckChar(pos, m)
+	}
 	if m.HasTag(KingSideCastle) {
 		return "O-O" + checkChar
 	} else if m.HasTag(QueenSideCastle) {
@@ -122,6 +161,17 @@ func (AlgebraicNotation) Encode(pos *Position, m *Move) string {
 	return pChar + s1Str + capChar + m.s2.String() + promoText + checkChar
 }
 
+// SAN returns the move in standard algebraic notation including
+// check and checkmate suffixes.  An error is returned if the move
+// isn't valid in the position.
+func SAN(pos *Position, m *Move) (string, error) {
+	valid := moveSlice(pos.validMovesRef()).find(m)
+	if valid == nil {
+		return "", fmt.Errorf("chess: invalid move %s for position %s", m, pos)
+	}
+	return AlgebraicNotation{}.Encode(pos, valid), nil
+}
+
 var pgnRegex = regexp.MustCompile(`^(?:([RNBQKP]?)([abcdefgh]?)(\d?)(x?)([abcdefgh])(\d)(=[QRBN])?|(O-O(?:-O)?))([+#!?]|e\.p\.)*$`)
 
 func algebraicNotationParts(s string) (string, string, string, string, string, string, string, string, error) {
@@ -175,9 +225,71 @@ func (AlgebraicNotation) Decode(pos *Position, s string) (*Move, error) {
 			}
 		}
 	}
+	if candidates := ambiguousCandidates(pos, piece, originFile, originRank, file, rank, promotes); len(candidates) > 1 {
+		return nil, fmt.Errorf("chess: ambiguous move \"%s\": %s", s, strings.Join(candidates, " or "))
+	}
 	return nil, fmt.Errorf("chess: could not decode algebraic notation %s for position %s", s, pos.String())
 }
 
+// ambiguousCandidates returns the algebraic notation of the valid
+// piece moves to the destination that match the partial origin.
+func ambiguousCandidates(pos *Position, piece, originFile, originRank, file, rank, promotes string) []string {
+	if piece == "" || piece == "P" {
+		return nil
+	}
+	candidates := []string{}
+	for _, m := range pos.validMovesRef() {
+		p := pos.board.Piece(m.s1)
+		if charFromPieceType(p.Type()) != piece || m.s2.String() != file+rank || charForPromo(m.promo) != promotes {
+			continue
+		}
+		if (originFile != "" && m.s1.File().String() != originFile) || (originRank != "" && m.s1.Rank().String() != originRank) {
+			continue
+		}
+		candidates = append(candidates, strings.TrimRight(AlgebraicNotation{}.Encode(pos, m), "+#"))
+	}
+	return candidates
+}
+
+// FigurineNotation is algebraic notation with the piece letters
+// replaced by Unicode chess glyphs in the color of the moving side.
+// Examples: ♘f3, ♞xe4, e8=♕ (promotion)
+type FigurineNotation struct{}
+
+// String implements the fmt.Stringer interface and returns
+// the notation's name.
+func (FigurineNotation) String() string {
+	return "Figurine Algebraic Notation"
+}
+
+// Encode implements the Encoder interface.
+func (FigurineNotation) Encode(pos *Position, m *Move) string {
+	c := pos.Board().Piece(m.S1()).Color()
+	return strings.Map(func(r rune) rune {
+		pt := pieceTypeFromChar(strings.ToLower(string(r)))
+		if r == 'K' {
+			pt = King
+		}
+		if pt == NoPieceType || r < 'A' || r > 'Z' {
+			return r
+		}
+		return []rune(NewPiece(pt, c).String())[0]
+	}, AlgebraicNotation{}.Encode(pos, m))
+}
+
+// figurineReplacer converts piece glyphs of either color to the
+// letters used by algebraic notation.
+var figurineReplacer = strings.NewReplacer(
+	"♔", "K", "♕", "Q", "♖", "R", "♗", "B", "♘", "N", "♙", "",
+	"♚", "K", "♛", "Q", "♜", "R", "♝", "B", "♞", "N", "♟", "",
+)
+
+// Decode implements the Decoder interface.  Glyphs of either color
+// and plain piece letters are accepted.
+func (FigurineNotation) Decode(pos *Position, s string) (*Move, error) {
+	return AlgebraicNotation{}.Decode(pos, figurineReplacer.Replace(s))
+}
+
 // LongAlgebraicNotation is a fully expanded version of
 // algebraic notation in which the starting and ending
 // squares are specified.
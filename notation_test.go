@@ -117,3 +117,67 @@ func TestInvalidDecoding(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateLine(t *testing.T) {
+	najdorf := []string{"e4", "c5", "Nf3", "d6", "d4", "cxd4", "Nxd4", "Nf6", "Nc3", "a6"}
+	if i, err := ValidateLine(startFEN, najdorf, AlgebraicNotation{}); i != -1 || err != nil {
+		t.Fatalf("expected line to be valid but got index %d and error %v", i, err)
+	}
+	invalid := append([]string(nil), najdorf...)
+	invalid[7] = "Nd5"
+	if i, err := ValidateLine(startFEN, invalid, AlgebraicNotation{}); i != 7 || err == nil {
+		t.Fatalf("expected invalid move at index %d but got index %d and error %v", 7, i, err)
+	}
+	uci := []string{"e2e4", "c7c5", "e4e5", "e8e6"}
+	if i, err := ValidateLine(startFEN, uci, UCINotation{}); i != 3 || err == nil {
+		t.Fatalf("expected invalid move at index %d but got index %d and error %v", 3, i, err)
+	}
+}
+
+func TestAlgebraicNotationAmbiguousMove(t *testing.T) {
+	pos := unsafeFEN("4k3/8/8/8/8/5N2/8/1N2K3 w - - 0 1")
+	_, err := AlgebraicNotation{}.Decode(pos, "Nd2")
+	if err == nil || err.Error() != `chess: ambiguous move "Nd2": Nbd2 or Nfd2` {
+		t.Fatalf("expected ambiguous move error but got %v", err)
+	}
+	m, err := AlgebraicNotation{}.Decode(pos, "Nfd2")
+	if err != nil || m.S1() != F3 {
+		t.Fatalf("expected Nfd2 to decode from f3 but got %v %v", m, err)
+	}
+}
+
+func TestAlgebraicNotationNoCheckSuffix(t *testing.T) {
+	pos := unsafeFEN("4k3/8/8/8/8/8/8/R3K3 w - - 0 1")
+	m := &Move{s1: A1, s2: A8}
+	addTags(m, pos)
+	if s := (AlgebraicNotation{}).Encode(pos, m); s != "Ra8+" {
+		t.Fatalf("expected Ra8+ but got %s", s)
+	}
+	n := AlgebraicNotation{NoCheckSuffix: true}
+	if s := n.Encode(pos, m); s != "Ra8" {
+		t.Fatalf("expected Ra8 but got %s", s)
+	}
+	for _, s := range []string{"Ra8", "Ra8+"} {
+		if _, err := n.Decode(pos, s); err != nil {
+			t.Fatalf("expected %s to decode but got %s", s, err)
+		}
+	}
+}
+
+func TestSAN(t *testing.T) {
+	pos := unsafeFEN("rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2")
+	if s, err := SAN(pos, &Move{s1: G1, s2: F3}); err != nil || s != "Nf3" {
+		t.Fatalf("expected Nf3 but got %s %v", s, err)
+	}
+	pos = unsafeFEN("r1bqk1nr/pppp1ppp/2n5/2b1p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 4 4")
+	if s, err := SAN(pos, &Move{s1: E1, s2: G1}); err != nil || s != "O-O" {
+		t.Fatalf("expected O-O but got %s %v", s, err)
+	}
+	pos = unsafeFEN("4k3/8/8/8/8/8/8/R3K3 w - - 0 1")
+	if s, err := SAN(pos, &Move{s1: A1, s2: A8}); err != nil || s != "Ra8+" {
+		t.Fatalf("expected Ra8+ but got %s %v", s, err)
+	}
+	if _, err := SAN(pos, &Move{s1: A1, s2: B2}); err == nil {
+		t.Fatal("expected error for an invalid move")
+	}
+}
@@ -0,0 +1,105 @@
+package chess
+
+// PawnsOnFile returns the number of pawns of the given color
+// on the file.
+func (pos *Position) PawnsOnFile(c Color, f File) int {
+	return (pos.board.bbForPiece(NewPiece(Pawn, c)) & bbFiles[f]).Count()
+}
+
+// IsolatedPawns returns the squares of the pawns of the given color
+// that have no friendly pawns on an adjacent file.
+func (pos *Position) IsolatedPawns(c Color) []Square {
+	return pos.pawnsWhere(c, func(sq Square) bool {
+		f := sq.File()
+		return (f == FileA || pos.PawnsOnFile(c, f-1) == 0) &&
+			(f == FileH || pos.PawnsOnFile(c, f+1) == 0)
+	})
+}
+
+// DoubledPawns returns the squares of the pawns of the given color
+// that share their file with another friendly pawn.
+func (pos *Position) DoubledPawns(c Color) []Square {
+	return pos.pawnsWhere(c, func(sq Square) bool {
+		return pos.PawnsOnFile(c, sq.File()) > 1
+	})
+}
+
+// PassedPawns returns the squares of the pawns of the given color
+// that have no enemy pawns in front of them on their own file or an
+// adjacent file.
+func (pos *Position) PassedPawns(c Color) []Square {
+	enemy := pos.board.bbForPiece(NewPiece(Pawn, c.Other()))
+	return pos.pawnsWhere(c, func(sq Square) bool {
+		for sq2 := 0; sq2 < numOfSquaresInBoard; sq2++ {
+			s := Square(sq2)
+			if !enemy.Occupied(s) {
+				continue
+			}
+			df := int(s.File()) - int(sq.File())
+			if df < -1 || df > 1 {
+				continue
+			}
+			if (c == White && s.Rank() > sq.Rank()) || (c == Black && s.Rank() < sq.Rank()) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// pawnsWhere returns the squares of the pawns of the given color
+// for which fn returns true.
+func (pos *Position) pawnsWhere(c Color, fn func(sq Square) bool) []Square {
+	pawns := pos.board.bbForPiece(NewPiece(Pawn, c))
+	sqs := []Square{}
+	for sq := 0; sq < numOfSquaresInBoard; sq++ {
+		if pawns.Occupied(Square(sq)) && fn(Square(sq)) {
+			sqs = append(sqs, Square(sq))
+		}
+	}
+	return sqs
+}
+
+// KingSafety returns a score for the pawn cover of the king of the
+// given color.  Each file next to or under the king adds two if a
+// friendly pawn is directly in front of the king and one if it is two
+// squares in front.  A file without friendly pawns subtracts one if it
+// is semi-open and two if it is open.  Higher scores are safer.
+func (pos *Position) KingSafety(c Color) int {
+	kingSq := pos.board.whiteKingSq
+	dir := 1
+	if c == Black {
+		kingSq = pos.board.blackKingSq
+		dir = -1
+	}
+	if kingSq == NoSquare {
+		return 0
+	}
+	pawn := NewPiece(Pawn, c)
+	score := 0
+	for f := int(kingSq.File()) - 1; f <= int(kingSq.File())+1; f++ {
+		if f < int(FileA) || f > int(FileH) {
+			continue
+		}
+		switch {
+		case pos.PawnsOnFile(c, File(f)) == 0 && pos.PawnsOnFile(c.Other(), File(f)) == 0:
+			score -= 2
+		case pos.PawnsOnFile(c, File(f)) == 0:
+			score--
+		case pos.pieceAt(File(f), int(kingSq.Rank())+dir) == pawn:
+			score += 2
+		case pos.pieceAt(File(f), int(kingSq.Rank())+2*dir) == pawn:
+			score++
+		}
+	}
+	return score
+}
+
+// pieceAt returns the piece on the file and rank or NoPiece if the
+// rank is off the board.
+func (pos *Position) pieceAt(f File, r int) Piece {
+	if r < int(Rank1) || r > int(Rank8) {
+		return NoPiece
+	}
+	return pos.board.Piece(NewSquare(f, Rank(r)))
+}
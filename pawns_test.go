@@ -0,0 +1,48 @@
+package chess
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPawnStructure(t *testing.T) {
+	pos := unsafeFEN("4k3/3p2pp/8/P7/8/4P3/4PP2/4K3 w - - 0 1")
+	if n := pos.PawnsOnFile(White, FileE); n != 2 {
+		t.Fatalf("expected 2 white pawns on the e file but got %d", n)
+	}
+	tests := []struct {
+		name     string
+		sqs      []Square
+		expected []Square
+	}{
+		{"white isolated", pos.IsolatedPawns(White), []Square{A5}},
+		{"black isolated", pos.IsolatedPawns(Black), []Square{D7}},
+		{"white doubled", pos.DoubledPawns(White), []Square{E2, E3}},
+		{"black doubled", pos.DoubledPawns(Black), []Square{}},
+		{"white passed", pos.PassedPawns(White), []Square{A5}},
+		{"black passed", pos.PassedPawns(Black), []Square{H7}},
+	}
+	for _, test := range tests {
+		if fmt.Sprint(test.sqs) != fmt.Sprint(test.expected) {
+			t.Fatalf("expected %s pawns %v but got %v", test.name, test.expected, test.sqs)
+		}
+	}
+}
+
+func TestKingSafety(t *testing.T) {
+	castled := unsafeFEN("6k1/5ppp/8/8/8/8/5PPP/6K1 w - - 0 1")
+	if score := castled.KingSafety(White); score != 6 {
+		t.Fatalf("expected intact shield to score 6 but got %d", score)
+	}
+	if score := castled.KingSafety(Black); score != 6 {
+		t.Fatalf("expected intact black shield to score 6 but got %d", score)
+	}
+	exposed := unsafeFEN("4k3/pp4pp/8/8/8/8/PP4PP/4K3 w - - 0 1")
+	if score := exposed.KingSafety(White); score >= castled.KingSafety(White) || score != -6 {
+		t.Fatalf("expected king on open files to score -6 but got %d", score)
+	}
+	advanced := unsafeFEN("6k1/5ppp/8/8/8/6P1/5P1P/6K1 w - - 0 1")
+	if score := advanced.KingSafety(White); score != 5 {
+		t.Fatalf("expected advanced shield pawn to score 5 but got %d", score)
+	}
+}
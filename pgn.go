@@ -2,10 +2,16 @@ package chess
 
 import (
 	"bufio"
+	"bytes"
+	"compress/gzip"
 	"fmt"
 	"io"
+	"os"
 	"regexp"
+	"strconv"
 	"strings"
+
+	"github.com/klauspost/compress/zstd"
 )
 
 // Scanner is modeled on the bufio.Scanner type but
@@ -60,11 +66,20 @@ func (s *Scanner) Scan() bool {
 			if s.err == nil {
 				s.err = io.EOF
 			}
+			// trailing blank lines don't start another game
+			if state == notInPGN {
+				return false
+			}
 			return setGame()
 		}
+		if isEscapeLine(s.scanr.Text()) {
+			continue
+		}
 		line := strings.TrimSpace(s.scanr.Text())
 		isTagPair := strings.HasPrefix(line, "[")
-		isMoveSeq := strings.HasPrefix(line, "1. ")
+		// move numbers may be missing or incorrect so any
+		// non tag pair text starts the move sequence
+		isMoveSeq := line != "" && !isTagPair
 		switch state {
 		case notInPGN:
 			if !isTagPair {
@@ -136,6 +151,53 @@ func GamesFromPGN(r io.Reader) ([]*Game, error) {
 	return games, nil
 }
 
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// GamesFromFile returns all games from the PGN file at the given
+// path.  Gzip and Zstandard compressed files are detected by their
+// magic bytes and decompressed transparently.
+func GamesFromFile(path string) ([]*Game, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	br := bufio.NewReader(f)
+	magic, err := br.Peek(len(zstdMagic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	var r io.Reader = br
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer gr.Close()
+		r = gr
+	case bytes.HasPrefix(magic, zstdMagic):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		r = zr
+	}
+	games := []*Game{}
+	scanner := NewScanner(r)
+	for scanner.Scan() {
+		games = append(games, scanner.Next())
+	}
+	if err := scanner.Err(); err != io.EOF {
+		return nil, err
+	}
+	return games, nil
+}
+
 type multiDecoder []Decoder
 
 func (a multiDecoder) Decode(pos *Position, s string) (*Move, error) {
@@ -149,12 +211,24 @@ func (a multiDecoder) Decode(pos *Position, s string) (*Move, error) {
 }
 
 func decodePGN(pgn string) (*Game, error) {
+	pgn = stripEscapeLines(pgn)
 	tagPairs := getTagPairs(pgn)
 	moveComments, outcome := moveListWithComments(pgn)
 	gameFuncs := []func(*Game){}
+	variant := Standard
+	for _, tp := range tagPairs {
+		if strings.ToLower(tp.Key) != "variant" {
+			continue
+		}
+		v, ok := variantFromTag(tp.Value)
+		if !ok {
+			return nil, fmt.Errorf("chess: pgn decode error unsupported variant %s", tp.Value)
+		}
+		variant = v
+	}
 	for _, tp := range tagPairs {
 		if strings.ToLower(tp.Key) == "fen" {
-			fenFunc, err := FEN(tp.Value)
+			fenFunc, err := variantFEN(variant, tp.Value)
 			if err != nil {
 				return nil, fmt.Errorf("chess: pgn decode error %s on tag %s", err.Error(), tp.Key)
 			}
@@ -162,11 +236,17 @@ func decodePGN(pgn string) (*Game, error) {
 			break
 		}
 	}
+	if variant != Standard {
+		gameFuncs = append(gameFuncs, UseVariant(variant))
+	}
 	gameFuncs = append(gameFuncs, TagPairs(tagPairs))
 	g := NewGame(gameFuncs...)
 	g.ignoreAutomaticDraws = true
-	decoder := multiDecoder([]Decoder{AlgebraicNotation{}, LongAlgebraicNotation{}, UCINotation{}})
+	decoder := multiDecoder([]Decoder{AlgebraicNotation{}, LongAlgebraicNotation{}, UCINotation{}, FigurineNotation{}})
 	for _, move := range moveComments {
+		if isNullMoveToken(move.MoveStr) {
+			return nil, fmt.Errorf("chess: pgn decode error null move %s is only supported in variations on move %d", move.MoveStr, g.Position().moveCount)
+		}
 		m, err := decoder.Decode(g.Position(), move.MoveStr)
 		if err != nil {
 			return nil, fmt.Errorf("chess: pgn decode error %s on move %d", err.Error(), g.Position().moveCount)
@@ -175,32 +255,101 @@ func decodePGN(pgn string) (*Game, error) {
 			return nil, fmt.Errorf("chess: pgn invalid move error %s on move %d", err.Error(), g.Position().moveCount)
 		}
 		g.comments = append(g.comments, move.Comments)
+		g.nags = append(g.nags, move.NAGs)
+		g.variations = append(g.variations, nil)
+		for _, v := range move.Variations {
+			g.variations[len(g.variations)-1] = append(g.variations[len(g.variations)-1], decodeVariation(g.positions[len(g.moves)-1], v))
+		}
+	}
+	// keep the computed outcome if the pgn omits the result token
+	if outcome != "" {
+		g.outcome = outcome
+	}
+	if g.method == NoMethod && (g.outcome == WhiteWon || g.outcome == BlackWon) {
+		g.method = terminationMethod(g.GetTagPair("Termination"))
 	}
-	g.outcome = outcome
 	return g, nil
 }
 
+// terminationMethod returns the method for the decisive results
+// described by a Termination tag pair or NoMethod.
+func terminationMethod(tp *TagPair) Method {
+	if tp == nil {
+		return NoMethod
+	}
+	switch strings.ToLower(strings.TrimSpace(tp.Value)) {
+	case "time forfeit":
+		return Timeout
+	case "rules infraction":
+		return Forfeit
+	case "abandoned":
+		return Abandoned
+	}
+	return NoMethod
+}
+
 func encodePGN(g *Game) string {
 	s := ""
 	for _, tag := range g.tagPairs {
-		s += fmt.Sprintf("[%s \"%s\"]\n", tag.Key, tag.Value)
+		s += fmt.Sprintf("[%s \"%s\"]\n", tag.Key, escapeTagValue(tag.Value))
+	}
+	if v := g.positions[0].variant; v != Standard && g.GetTagPair("Variant") == nil {
+		s += fmt.Sprintf("[Variant \"%s\"]\n", v)
+	}
+	if t := g.Termination(); t != "" && g.GetTagPair("Termination") == nil {
+		s += fmt.Sprintf("[Termination \"%s\"]\n", t)
 	}
 	s += "\n"
+	return s + encodeMovetext(g)
+}
+
+// encodeMovetext encodes the moves, comments, variations, and result
+// token of the game without the tag pairs.
+func encodeMovetext(g *Game) string {
+	s := ""
+	interrupted := false
 	for i, move := range g.moves {
 		pos := g.positions[i]
-		txt := g.notation.Encode(pos, move)
-		if i%2 == 0 {
-			s += fmt.Sprintf("%d. %s", (i/2)+1, txt)
-		} else {
+		txt := g.outputNotation.Encode(pos, move)
+		// number moves from the starting position which may be
+		// loaded from a FEN with black to move and black moves
+		// that follow a comment or variation
+		switch {
+		case pos.turn == White:
+			s += fmt.Sprintf("%d. %s", pos.moveCount, txt)
+		case i == 0 || interrupted:
+			s += fmt.Sprintf("%d... %s ", pos.moveCount, txt)
+		default:
 			s += fmt.Sprintf(" %s ", txt)
 		}
+		if len(g.nags) > i {
+			for _, nag := range g.nags[i] {
+				if !strings.HasSuffix(s, " ") {
+					s += " "
+				}
+				s += fmt.Sprintf("$%d ", nag)
+			}
+		}
+		interrupted = false
 		if len(g.comments) > i {
 			for _, c := range g.comments[i] {
 				s += " { " + c + " } "
+				interrupted = true
+			}
+		}
+		if len(g.variations) > i {
+			for _, v := range g.variations[i] {
+				s += " (" + formatVariation(pos, v, g.outputNotation) + ") "
+				interrupted = true
 			}
 		}
 	}
-	s += " " + string(g.outcome)
+	// always terminate the movetext with a result token
+	outcome := g.outcome
+	if outcome == "" {
+		outcome = NoOutcome
+	}
+	s += " " + string(outcome)
 	return s
 }
 
@@ -216,7 +365,7 @@ func getTagPairs(pgn string) []*TagPair {
 		if len(results) == 3 {
 			pair := &TagPair{
 				Key:   results[1],
-				Value: results[2],
+				Value: unescapeTagValue(results[2]),
 			}
 			tagPairs = append(tagPairs, pair)
 		}
@@ -224,40 +373,201 @@ func getTagPairs(pgn string) []*TagPair {
 	return tagPairs
 }
 
+var tagValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// escapeTagValue escapes backslashes and quotes in a tag value.
+func escapeTagValue(s string) string {
+	return tagValueEscaper.Replace(s)
+}
+
+// unescapeTagValue reverses escapeTagValue.  Backslashes that don't
+// start an escape sequence are kept as is.
+func unescapeTagValue(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && (s[i+1] == '\\' || s[i+1] == '"') {
+			i++
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
 type moveWithComment struct {
-	MoveStr  string
-	Comments []string
+	MoveStr    string
+	Comments   []string
+	Variations []string
+	NAGs       []int
+}
+
+var moveListTokenRe = regexp.MustCompile(`(?:\d+\.)|(--|Z0|O-O(?:-O)?|[\w\x{2654}-\x{265F}]*[abcdefgh][12345678]\w*(?:=[QRBN\x{2654}-\x{265F}])?(?:\+|#)?)([!?]{1,2})?|(?:\{([^}]*)\})|(?:\(([^)]*)\))|(\*|0-1|1-0|1\/2-1\/2)|\$(\d+)`)
+
+// suffixNAGs maps the traditional move suffix annotations to their
+// numeric annotation glyphs.
+var suffixNAGs = map[string]int{
+	"!":  1,
+	"?":  2,
+	"!!": 3,
+	"??": 4,
+	"!?": 5,
+	"?!": 6,
+}
+
+// isNullMoveToken returns true if s is one of the PGN
+// conventions for a null move.
+func isNullMoveToken(s string) bool {
+	return s == "--" || s == "Z0"
+}
+
+// decodeVariation replays the variation text from pos, the position
+// before the move it replaces, and returns it in canonical form with
+// null moves written as "--".  Variations that contain comments or
+// can't be replayed are returned unchanged.
+func decodeVariation(pos *Position, text string) string {
+	text = strings.TrimSpace(text)
+	moves, ok := parseVariation(pos, text)
+	if !ok {
+		return text
+	}
+	return encodeVariation(pos, moves, AlgebraicNotation{})
 }
 
-var moveListTokenRe = regexp.MustCompile(`(?:\d+\.)|(O-O(?:-O)?|\w*[abcdefgh][12345678]\w*(?:=[QRBN])?(?:\+|#)?)|(?:\{([^}]*)\})|(?:\([^)]*\))|(\*|0-1|1-0|1\/2-1\/2)`)
+// formatVariation returns the variation text played from pos in the
+// given notation.  Variations are stored in algebraic notation so
+// they are encoded again whenever the output notation differs.
+// Variations that can't be replayed are returned unchanged.
+func formatVariation(pos *Position, text string, enc Encoder) string {
+	if enc == (AlgebraicNotation{}) {
+		return text
+	}
+	moves, ok := parseVariation(pos, text)
+	if !ok {
+		return text
+	}
+	return encodeVariation(pos, moves, enc)
+}
+
+// parseVariation returns the moves of the variation text played from
+// pos with nil for null moves.  The bool result is false if the text
+// contains anything other than moves and move numbers or a move
+// can't be decoded.
+func parseVariation(pos *Position, text string) ([]*Move, bool) {
+	decoder := multiDecoder([]Decoder{AlgebraicNotation{}, LongAlgebraicNotation{}, UCINotation{}, FigurineNotation{}})
+	moves := []*Move{}
+	for _, match := range moveListTokenRe.FindAllStringSubmatch(text, -1) {
+		move := match[1]
+		if match[2]+match[3]+match[4]+match[5]+match[6] != "" {
+			return nil, false
+		}
+		if move == "" {
+			continue
+		}
+		if isNullMoveToken(move) {
+			moves = append(moves, nil)
+			pos = pos.NullMove()
+			continue
+		}
+		m, err := decoder.Decode(pos, move)
+		if err != nil {
+			return nil, false
+		}
+		moves = append(moves, m)
+		pos = pos.Update(m)
+	}
+	return moves, len(moves) > 0
+}
 
+// encodeVariation encodes the moves played from pos in the given
+// notation with move numbers.  Nil moves are encoded as null moves.
+func encodeVariation(pos *Position, moves []*Move, enc Encoder) string {
+	parts := []string{}
+	for i, m := range moves {
+		if pos.turn == White {
+			parts = append(parts, fmt.Sprintf("%d.", pos.moveCount))
+		} else if i == 0 {
+			parts = append(parts, fmt.Sprintf("%d...", pos.moveCount))
+		}
+		if m == nil {
+			parts = append(parts, "--")
+			pos = pos.NullMove()
+			continue
+		}
+		parts = append(parts, enc.Encode(pos, m))
+		pos = pos.Update(m)
+	}
+	return strings.Join(parts, " ")
+}
+
+// moveListWithComments returns the moves in the order they appear.
+// Move number tokens are matched but discarded so that PGNs with
+// incorrect or missing move numbers are decoded by move order.
 func moveListWithComments(pgn string) ([]moveWithComment, Outcome) {
 	pgn = stripTagPairs(pgn)
 	var outcome Outcome
 	moves := []moveWithComment{}
 
 	for _, match := range moveListTokenRe.FindAllStringSubmatch(pgn, -1) {
-		move, commentText, outcomeText := match[1], match[2], match[3]
-		if len(move+commentText+outcomeText) == 0 {
+		move, suffix, commentText, variationText, outcomeText, nagText := match[1], match[2], match[3], match[4], match[5], match[6]
+		if len(move+commentText+variationText+outcomeText+nagText) == 0 {
 			continue
 		}
 
+		// a top level result token ends the mainline but trailing
+		// comments and variations are still attached to the last move
 		if outcomeText != "" {
-			outcome = Outcome(outcomeText)
-			break
+			if outcome == "" {
+				outcome = Outcome(outcomeText)
+			}
+			continue
 		}
 
-		if commentText != "" {
+		if commentText != "" && len(moves) > 0 {
 			moves[len(moves)-1].Comments = append(moves[len(moves)-1].Comments, strings.TrimSpace(commentText))
 		}
 
-		if move != "" {
+		if variationText != "" && len(moves) > 0 {
+			moves[len(moves)-1].Variations = append(moves[len(moves)-1].Variations, variationText)
+		}
+
+		if nagText != "" && len(moves) > 0 {
+			nag, _ := strconv.Atoi(nagText)
+			moves[len(moves)-1].NAGs = append(moves[len(moves)-1].NAGs, nag)
+		}
+
+		if move != "" && outcome == "" {
 			moves = append(moves, moveWithComment{MoveStr: move})
+			if nag, ok := suffixNAGs[suffix]; ok {
+				moves[len(moves)-1].NAGs = append(moves[len(moves)-1].NAGs, nag)
+			}
 		}
 	}
 	return moves, outcome
 }
 
+// isEscapeLine returns true if the line starts with the PGN escape
+// character "%" which makes the rest of the line ignored.
+func isEscapeLine(line string) bool {
+	return strings.HasPrefix(line, "%")
+}
+
+// stripEscapeLines removes the lines escaped with "%".
+func stripEscapeLines(pgn string) string {
+	if !strings.Contains(pgn, "%") {
+		return pgn
+	}
+	lines := strings.Split(pgn, "\n")
+	cp := lines[:0]
+	for _, line := range lines {
+		if !isEscapeLine(line) {
+			cp = append(cp, line)
+		}
+	}
+	return strings.Join(cp, "\n")
+}
+
 func stripTagPairs(pgn string) string {
 	lines := strings.Split(pgn, "\n")
 	cp := []string{}
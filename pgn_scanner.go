@@ -0,0 +1,184 @@
+package chess
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// TokenType is the type of a PGN token.
+type TokenType int
+
+const (
+	// TagPairToken is a tag pair such as [Event "Casual Game"].
+	TagPairToken TokenType = iota
+	// MoveNumberToken is a move number indication such as 1. or 1...
+	MoveNumberToken
+	// MoveToken is a move in any notation such as e4 or Nf3+.
+	MoveToken
+	// CommentToken is a brace or rest of line comment.
+	CommentToken
+	// NAGToken is a numeric annotation glyph such as $1.
+	NAGToken
+	// VariationStartToken is the opening parenthesis of a variation.
+	VariationStartToken
+	// VariationEndToken is the closing parenthesis of a variation.
+	VariationEndToken
+	// ResultToken is a game termination marker such as 1-0 or *.
+	ResultToken
+)
+
+// String implements the fmt.Stringer interface
+func (t TokenType) String() string {
+	switch t {
+	case TagPairToken:
+		return "TagPair"
+	case MoveNumberToken:
+		return "MoveNumber"
+	case MoveToken:
+		return "Move"
+	case CommentToken:
+		return "Comment"
+	case NAGToken:
+		return "NAG"
+	case VariationStartToken:
+		return "VariationStart"
+	case VariationEndToken:
+		return "VariationEnd"
+	case ResultToken:
+		return "Result"
+	}
+	return ""
+}
+
+// Token is a single lexical element of PGN text.  Key is only set for
+// tag pairs.  Value is the tag value for tag pairs, the text for
+// comments, and the token's text for everything else.
+type Token struct {
+	Type  TokenType
+	Key   string
+	Value string
+}
+
+// PGNScanner reads typed tokens from PGN text without building games.
+// It is modeled on the bufio.Scanner type and can process very large
+// PGN files line by line.
+type PGNScanner struct {
+	scanr     *bufio.Scanner
+	pending   []Token
+	token     Token
+	comment   strings.Builder
+	inComment bool
+}
+
+// NewPGNScanner returns a new PGN token scanner.
+func NewPGNScanner(r io.Reader) *PGNScanner {
+	return &PGNScanner{scanr: bufio.NewScanner(r)}
+}
+
+// Scan advances to the next token which is then available through
+// Token.  It returns false at the end of the input or on an error.
+func (s *PGNScanner) Scan() bool {
+	for len(s.pending) == 0 {
+		if !s.scanr.Scan() {
+			return false
+		}
+		s.tokenizeLine(s.scanr.Text())
+	}
+	s.token, s.pending = s.pending[0], s.pending[1:]
+	return true
+}
+
+// Token returns the token from the most recent Scan.
+func (s *PGNScanner) Token() Token {
+	return s.token
+}
+
+// Err returns the first non EOF error encountered while reading.
+func (s *PGNScanner) Err() error {
+	return s.scanr.Err()
+}
+
+func (s *PGNScanner) emit(t TokenType, value string) {
+	s.pending = append(s.pending, Token{Type: t, Value: value})
+}
+
+func (s *PGNScanner) tokenizeLine(line string) {
+	if !s.inComment && isEscapeLine(line) {
+		return
+	}
+	if !s.inComment && strings.HasPrefix(strings.TrimSpace(line), "[") {
+		for _, tp := range getTagPairs(line) {
+			s.pending = append(s.pending, Token{Type: TagPairToken, Key: tp.Key, Value: tp.Value})
+		}
+		return
+	}
+	for i := 0; i < len(line); {
+		if s.inComment {
+			end := strings.IndexByte(line[i:], '}')
+			if end == -1 {
+				s.comment.WriteString(line[i:] + "\n")
+				return
+			}
+			s.comment.WriteString(line[i : i+end])
+			s.emit(CommentToken, strings.TrimSpace(s.comment.String()))
+			s.comment.Reset()
+			s.inComment = false
+			i += end + 1
+			continue
+		}
+		c := line[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '{':
+			s.inComment = true
+			i++
+		case c == ';':
+			s.emit(CommentToken, strings.TrimSpace(line[i+1:]))
+			return
+		case c == '(':
+			s.emit(VariationStartToken, "(")
+			i++
+		case c == ')':
+			s.emit(VariationEndToken, ")")
+			i++
+		default:
+			j := i + 1
+			for j < len(line) && !strings.ContainsRune(" \t\r{};()", rune(line[j])) {
+				j++
+			}
+			s.emitWord(line[i:j])
+			i = j
+		}
+	}
+}
+
+// emitWord emits the tokens for text without whitespace or
+// delimiters such as "12.", "12...e5", "$1", "1-0", or "Nf3".
+func (s *PGNScanner) emitWord(word string) {
+	switch word {
+	case "1-0", "0-1", "1/2-1/2", "*":
+		s.emit(ResultToken, word)
+		return
+	}
+	if word[0] == '$' {
+		s.emit(NAGToken, word)
+		return
+	}
+	n := 0
+	for n < len(word) && word[n] >= '0' && word[n] <= '9' {
+		n++
+	}
+	if n > 0 && n < len(word) && word[n] == '.' {
+		for n < len(word) && word[n] == '.' {
+			n++
+		}
+		s.emit(MoveNumberToken, word[:n])
+		if n < len(word) {
+			s.emitWord(word[n:])
+		}
+		return
+	}
+	s.emit(MoveToken, word)
+}
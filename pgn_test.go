@@ -1,10 +1,16 @@
 package chess
 
 import (
+	"compress/gzip"
+	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/klauspost/compress/zstd"
 )
 
 type pgnTest struct {
@@ -120,6 +126,117 @@ func TestNewGameComments(t *testing.T) {
 	}
 }
 
+func TestDiscardComments(t *testing.T) {
+	for _, test := range commentTests {
+		pgn, err := PGN(strings.NewReader(test.PGN))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, opts := range [][]func(*Game){
+			{DiscardComments(), pgn},
+			{pgn, DiscardComments()},
+		} {
+			game := NewGame(opts...)
+			if len(game.Comments()) != 0 {
+				t.Fatalf("expected no comments but got %v", game.Comments())
+			}
+			if strings.Contains(game.String(), "{") || strings.Contains(game.String(), "(") {
+				t.Fatalf("expected pgn without comments or variations but got %s", game.String())
+			}
+			for _, mh := range game.MoveHistory() {
+				if len(mh.Comments) != 0 {
+					t.Fatalf("expected no move history comments but got %v", mh.Comments)
+				}
+			}
+		}
+	}
+}
+
+func TestGameMerge(t *testing.T) {
+	a, err := decodePGN("1. e4 $1 { best by test } e5 2. Nf3 (2. f4 exf4) Nc6 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := decodePGN("1. e4 $1 $14 { best by test } e5 $6 { solid } 2. Nf3 (2. Bc4 Nf6) Nc6 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	comments := a.Comments()
+	if len(comments[0]) != 1 || len(comments[1]) != 1 || comments[1][0] != "solid" {
+		t.Fatalf("unexpected merged comments %v", comments)
+	}
+	history := a.MoveHistory()
+	if !reflect.DeepEqual(history[0].NAGs, []int{1, 14}) || !reflect.DeepEqual(history[1].NAGs, []int{6}) {
+		t.Fatalf("unexpected merged NAGs %v and %v", history[0].NAGs, history[1].NAGs)
+	}
+	s := a.String()
+	if !strings.Contains(s, "(2. f4 exf4)") || !strings.Contains(s, "(2. Bc4 Nf6)") {
+		t.Fatalf("expected both variations in merged pgn but got %s", s)
+	}
+	c, err := decodePGN("1. e4 e5 2. Bc4 Nc6 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Merge(c); err == nil {
+		t.Fatal("expected games with different moves to fail to merge")
+	}
+}
+
+func TestPGNTagValueEscaping(t *testing.T) {
+	value := `The "Old" Club \ Annex`
+	g := NewGame(TagPairs([]*TagPair{{Key: "Site", Value: value}}))
+	if err := g.MoveStr("e4"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(g.String(), `[Site "The \"Old\" Club \\ Annex"]`) {
+		t.Fatalf("expected escaped tag value but got %s", g.String())
+	}
+	game, err := decodePGN(g.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := game.GetTagPair("Site").Value; v != value {
+		t.Fatalf("expected tag value %s but got %s", value, v)
+	}
+}
+
+func TestGameMovetext(t *testing.T) {
+	game, err := decodePGN(mustParsePGN("fixtures/pgns/0001.pgn"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	movetext := game.Movetext()
+	if strings.Contains(movetext, "[Event") || strings.HasPrefix(movetext, "\n") {
+		t.Fatalf("expected movetext without tag pairs but got %s", movetext)
+	}
+	if !strings.HasSuffix(movetext, " "+string(game.Outcome())) {
+		t.Fatalf("expected movetext to end with the result token but got %s", movetext)
+	}
+	if !strings.HasSuffix(game.String(), movetext) {
+		t.Fatalf("expected movetext to match the end of the pgn")
+	}
+}
+
+func TestPGNExportFromMidGameFEN(t *testing.T) {
+	fen, err := FEN("r1bqkbnr/pppp1ppp/2n5/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R b KQkq - 3 24")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGame(fen)
+	for _, m := range []string{"Nf6", "Ng5", "d5"} {
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := "24... Nf6 25. Ng5 d5  *"
+	if movetext := g.Movetext(); movetext != expected {
+		t.Fatalf("expected movetext %q but got %q", expected, movetext)
+	}
+}
+
 func TestWriteComments(t *testing.T) {
 	pgn := mustParsePGN("fixtures/pgns/0005.pgn")
 	game, err := decodePGN(pgn)
@@ -154,6 +271,259 @@ func TestScanner(t *testing.T) {
 	}
 }
 
+func TestGamesFromCompressedFile(t *testing.T) {
+	pgn := "[Event \"A\"]\n\n1. e4 e5 2. Nf3 *\n\n[Event \"B\"]\n\n1. d4 d5 1/2-1/2\n\n"
+	compressors := map[string]func(io.Writer) io.WriteCloser{
+		"games.pgn.gz": func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+		"games.pgn.zst": func(w io.Writer) io.WriteCloser {
+			zw, _ := zstd.NewWriter(w)
+			return zw
+		},
+	}
+	for name, compress := range compressors {
+		path := filepath.Join(t.TempDir(), name)
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := compress(f)
+		if _, err := w.Write([]byte(pgn)); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+		games, err := GamesFromFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(games) != 2 {
+			t.Fatalf("expected 2 games from %s but got %d", name, len(games))
+		}
+		if len(games[0].Moves()) != 3 || games[1].Outcome() != Draw {
+			t.Fatalf("unexpected games from %s %s and %s", name, games[0], games[1])
+		}
+	}
+	games, err := GamesFromFile("fixtures/pgns/0006.pgn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 5 {
+		t.Fatalf("expected 5 uncompressed games but got %d", len(games))
+	}
+}
+
+func TestPGNVariantTag(t *testing.T) {
+	pgn := `[Variant "From Position"]
+[FEN "4k3/8/8/8/8/8/8/4K2R w K - 0 1"]
+
+1. O-O *`
+	game, err := decodePGN(pgn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game.FEN() != "4k3/8/8/8/8/8/8/5RK1 b - - 1 1" {
+		t.Fatalf("expected castled position but got %s", game.FEN())
+	}
+	chess960 := `[Variant "Chess960"]
+[FEN "1r4kr/8/8/8/8/8/8/1R4KR w HBhb - 0 1"]
+
+1. O-O-O O-O *`
+	game, err = decodePGN(chess960)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game.FEN() != "1r3rk1/8/8/8/8/8/8/2KR3R w - - 2 2" {
+		t.Fatalf("expected chess960 castled position but got %s", game.FEN())
+	}
+	if uci := game.MovesUCI(); len(uci) != 2 || uci[0] != "g1b1" || uci[1] != "g8h8" {
+		t.Fatalf("expected chess960 castles g1b1 and g8h8 but got %v", uci)
+	}
+	if _, err := decodePGN("[Variant \"Crazyhouse\"]\n\n1. e4 *"); err == nil || !strings.Contains(err.Error(), "Crazyhouse") {
+		t.Fatalf("expected unsupported variant error but got %v", err)
+	}
+}
+
+func TestPGNScanner(t *testing.T) {
+	pgn := `[Event "Casual \"Blitz\""]
+[Result "1-0"]
+
+1. e4 {best by
+test} e5 2.Nf3 $1 (2. f4 exf4) 2... Nc6 ; rest of line
+3. Bb5 1-0`
+	expected := []Token{
+		{Type: TagPairToken, Key: "Event", Value: `Casual "Blitz"`},
+		{Type: TagPairToken, Key: "Result", Value: "1-0"},
+		{Type: MoveNumberToken, Value: "1."},
+		{Type: MoveToken, Value: "e4"},
+		{Type: CommentToken, Value: "best by\ntest"},
+		{Type: MoveToken, Value: "e5"},
+		{Type: MoveNumberToken, Value: "2."},
+		{Type: MoveToken, Value: "Nf3"},
+		{Type: NAGToken, Value: "$1"},
+		{Type: VariationStartToken, Value: "("},
+		{Type: MoveNumberToken, Value: "2."},
+		{Type: MoveToken, Value: "f4"},
+		{Type: MoveToken, Value: "exf4"},
+		{Type: VariationEndToken, Value: ")"},
+		{Type: MoveNumberToken, Value: "2..."},
+		{Type: MoveToken, Value: "Nc6"},
+		{Type: CommentToken, Value: "rest of line"},
+		{Type: MoveNumberToken, Value: "3."},
+		{Type: MoveToken, Value: "Bb5"},
+		{Type: ResultToken, Value: "1-0"},
+	}
+	scanner := NewPGNScanner(strings.NewReader(pgn))
+	tokens := []Token{}
+	for scanner.Scan() {
+		tokens = append(tokens, scanner.Token())
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != len(expected) {
+		t.Fatalf("expected %d tokens but got %d: %v", len(expected), len(tokens), tokens)
+	}
+	for i := range expected {
+		if tokens[i] != expected[i] {
+			t.Fatalf("expected token %d to be %+v but got %+v", i, expected[i], tokens[i])
+		}
+	}
+}
+
+func TestPGNIncorrectMoveNumbers(t *testing.T) {
+	expected := "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3"
+	for _, pgn := range []string{
+		"1. e4 e5 3. Nf3 Nc6 *",
+		"e4 e5 Nf3 Nc6 *",
+		"1. e4 1... e5 7. Nf3 7... Nc6 *",
+	} {
+		game, err := decodePGN(pgn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if game.FEN() != expected {
+			t.Fatalf("expected %s to decode to %s but got %s", pgn, expected, game.FEN())
+		}
+	}
+}
+
+func TestScannerMissingMoveNumbers(t *testing.T) {
+	pgn := "[Event \"A\"]\n\ne4 e5 Nf3 *\n\n[Event \"B\"]\n\n3. d4 d5 *\n"
+	scanner := NewScanner(strings.NewReader(pgn))
+	games := []*Game{}
+	for scanner.Scan() {
+		games = append(games, scanner.Next())
+	}
+	if len(games) != 2 {
+		t.Fatalf("expected 2 games but got %d", len(games))
+	}
+	if len(games[0].Moves()) != 3 || len(games[1].Moves()) != 2 {
+		t.Fatalf("expected 3 and 2 moves but got %d and %d", len(games[0].Moves()), len(games[1].Moves()))
+	}
+}
+
+func TestPGNResultToken(t *testing.T) {
+	g := NewGame()
+	g.MoveStr("e4")
+	g.MoveStr("e5")
+	if !strings.HasSuffix(g.String(), " *") {
+		t.Fatalf("expected in progress pgn to end with * but got %s", g.String())
+	}
+	g.Resign(Black)
+	if !strings.HasSuffix(g.String(), " 1-0") {
+		t.Fatalf("expected decided pgn to end with 1-0 but got %s", g.String())
+	}
+	game, err := decodePGN("1. e4 e5 2. Nf3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game.Outcome() != NoOutcome || !strings.HasSuffix(game.String(), " *") {
+		t.Fatalf("expected pgn without result to end with * but got %s", game.String())
+	}
+}
+
+func TestPGNNullMoveVariation(t *testing.T) {
+	for _, pgn := range []string{
+		"1. e4 e5 (1... -- 2. d4) 2. Nf3 *",
+		"1. e4 e5 (1... Z0 2. d4) 2. Nf3 *",
+	} {
+		game, err := decodePGN(pgn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(game.String(), "(1... -- 2. d4)") {
+			t.Fatalf("expected %s to re-emit the null move variation but got %s", pgn, game.String())
+		}
+		game, err = decodePGN(game.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(game.String(), "(1... -- 2. d4)") || len(game.Moves()) != 3 {
+			t.Fatalf("expected null move variation to round trip but got %s", game.String())
+		}
+	}
+	game, err := decodePGN("1. e4 (1. -- e5) e5 2. Nf3 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(game.String(), "(1. -- e5) 1... e5") {
+		t.Fatalf("expected black move after a variation to be numbered but got %s", game.String())
+	}
+	game, err = decodePGN(game.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(game.Moves()) != 3 || !strings.Contains(game.String(), "(1. -- e5) 1... e5") {
+		t.Fatalf("expected numbered black move to round trip but got %s", game.String())
+	}
+	if _, err := decodePGN("1. e4 -- 2. d4 *"); err == nil {
+		t.Fatal("expected null move in the main line to be rejected")
+	}
+}
+
+func TestPGNResultTokenBeforeVariation(t *testing.T) {
+	pgn := "1. e4 e5 2. Qh5 Nc6 3. Bc4 Nf6 4. Qxf7# 1-0 (4. Qf3 {also strong} Nd4 5. Qxf7# 1-0) {analysis ends}"
+	game, err := decodePGN(pgn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(game.Moves()) != 7 || game.Outcome() != WhiteWon {
+		t.Fatalf("expected 7 moves and 1-0 but got %d moves and %s", len(game.Moves()), game.Outcome())
+	}
+	if !strings.Contains(game.String(), "(4. Qf3 {also strong} Nd4 5. Qxf7# 1-0)") {
+		t.Fatalf("expected trailing variation to be kept but got %s", game.String())
+	}
+	if c := game.Comments()[6]; len(c) != 1 || c[0] != "analysis ends" {
+		t.Fatalf("expected trailing comment on the last move but got %v", c)
+	}
+	game, err = decodePGN("1. e4 e5 (1... f6 2. d4 g5 3. Qh5# 1-0) 2. Nf3 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(game.Moves()) != 3 || game.Outcome() != NoOutcome {
+		t.Fatalf("expected result in variation to be ignored but got %d moves and %s", len(game.Moves()), game.Outcome())
+	}
+	// comments without a move to attach to are dropped
+	game, err = decodePGN("[Result \"1-0\"]\n\n1-0 {White wins by forfeit}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(game.Moves()) != 0 || game.Outcome() != WhiteWon {
+		t.Fatalf("expected no moves and 1-0 but got %d moves and %s", len(game.Moves()), game.Outcome())
+	}
+	game, err = decodePGN("{intro} 1. e4 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(game.Moves()) != 1 {
+		t.Fatalf("expected 1 move after a leading comment but got %d", len(game.Moves()))
+	}
+}
+
 func BenchmarkPGN(b *testing.B) {
 	pgn := mustParsePGN("fixtures/pgns/0001.pgn")
 	b.ResetTimer()
@@ -187,3 +557,94 @@ func TestGamesFromPGN(t *testing.T) {
 		}
 	}
 }
+
+func TestMovesByQuality(t *testing.T) {
+	pgn := `1. e4 e5 2. Nf3 Nc6 3. Bc4 h6? 4. Nc3 $1 a6 $2 { Too slow } 5. d4 b5?? *`
+	g, err := decodePGN(pgn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mistakes := g.MovesByQuality(2)
+	if len(mistakes) != 2 || mistakes[0].SAN != "h6" || mistakes[1].SAN != "a6" {
+		t.Fatalf("expected mistakes h6 and a6 but got %v", mistakes)
+	}
+	if blunders := g.MovesByQuality(4); len(blunders) != 1 || blunders[0].SAN != "b5" {
+		t.Fatalf("expected blunder b5 but got %v", blunders)
+	}
+	if !strings.Contains(g.String(), "h6 $2 ") || !strings.Contains(g.String(), "Nc3 $1 ") {
+		t.Fatalf("expected nags to be encoded but got %s", g.String())
+	}
+}
+
+func TestTermination(t *testing.T) {
+	g := NewGame()
+	g.MoveStr("e4")
+	if g.Termination() != "" || strings.Contains(g.String(), "Termination") {
+		t.Fatalf("expected no termination for a game in progress but got %s", g.String())
+	}
+	g.Resign(Black)
+	if g.Termination() != "Normal" || !strings.Contains(g.String(), `[Termination "Normal"]`) {
+		t.Fatalf("expected normal termination for resignation but got %s", g.String())
+	}
+	g, err := decodePGN(`[Termination "Time forfeit"]
+
+1. e4 e5 0-1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Method() != Timeout || g.Termination() != "Time forfeit" {
+		t.Fatalf("expected time forfeit but got %s", g.Method())
+	}
+	if c := strings.Count(g.String(), "[Termination"); c != 1 {
+		t.Fatalf("expected one termination tag but got %d", c)
+	}
+}
+
+func TestFigurinePGN(t *testing.T) {
+	g, err := decodePGN("1. ♘f3 ♞f6 2. e4 ♞xe4 3. ♗c4 e6 4. O-O ♛f6 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "1. Nf3 Nf6 2. e4 Nxe4 3. Bc4 e6 4. O-O Qf6 *"
+	if s := strings.Join(strings.Fields(g.Movetext()), " "); s != expected {
+		t.Fatalf("expected %s but got %s", expected, s)
+	}
+	g.outputNotation = FigurineNotation{}
+	if s := strings.Join(strings.Fields(g.Movetext()), " "); s != "1. ♘f3 ♞f6 2. e4 ♞xe4 3. ♗c4 e6 4. O-O ♛f6 *" {
+		t.Fatalf("unexpected figurine movetext %s", s)
+	}
+}
+
+func TestPGNEscapeLines(t *testing.T) {
+	pgn := `% generated by a test
+[Event "Escaped"]
+% tags can be interleaved with escaped lines
+[Site "?"]
+
+1. e4 e5 2. Nf3 { [%clk 0:05:00] }
+%  2... Nc6 is ignored
+2... Nf6 *
+
+% trailing data
+[Event "Second"]
+
+1. d4 *
+`
+	scanner := NewScanner(strings.NewReader(pgn))
+	games := []*Game{}
+	for scanner.Scan() {
+		games = append(games, scanner.Next())
+	}
+	if len(games) != 2 {
+		t.Fatalf("expected 2 games but got %d", len(games))
+	}
+	if n := len(games[0].Moves()); n != 4 || games[0].GetTagPair("Site") == nil {
+		t.Fatalf("expected 4 moves and the site tag but got %d moves", n)
+	}
+	if games[0].Moves()[3].S2() != F6 {
+		t.Fatal("expected the escaped move to be ignored")
+	}
+	if len(games[0].Comments()[2]) != 1 {
+		t.Fatal("expected the clock comment to be kept")
+	}
+}
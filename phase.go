@@ -0,0 +1,85 @@
+package chess
+
+// Phase is the stage of the game as determined by the
+// remaining material and the move number.
+type Phase uint8
+
+const (
+	// NoPhase indicates that the phase couldn't be determined.
+	NoPhase Phase = iota
+	// Opening indicates the first moves of the game while
+	// most of the material is still on the board.
+	Opening
+	// Middlegame indicates the phase between the opening
+	// and the endgame.
+	Middlegame
+	// Endgame indicates that most of the material has
+	// been traded off the board.
+	Endgame
+)
+
+// String implements the fmt.Stringer interface
+func (p Phase) String() string {
+	switch p {
+	case Opening:
+		return "Opening"
+	case Middlegame:
+		return "Middlegame"
+	case Endgame:
+		return "Endgame"
+	}
+	return "NoPhase"
+}
+
+const (
+	// MaxPhaseValue is the phase value of a position with all
+	// non pawn material on the board.
+	MaxPhaseValue = 24
+
+	openingMaxMoveCount = 10
+	openingMinPhase     = 22
+	endgameMaxPhase     = 8
+	queenlessMaxPhase   = 12
+)
+
+var (
+	phaseWeights = map[PieceType]int{
+		Queen:  4,
+		Rook:   2,
+		Bishop: 1,
+		Knight: 1,
+	}
+)
+
+// PhaseValue returns the tapered evaluation phase of the position
+// from MaxPhaseValue (all non pawn material on the board) to zero
+// (only kings and pawns).  Knights and bishops count as one, rooks
+// as two and queens as four.  Promoted pieces can't increase the
+// value past MaxPhaseValue.
+func (pos *Position) PhaseValue() int {
+	v := 0
+	for _, p := range pos.board.SquareMap() {
+		v += phaseWeights[p.Type()]
+	}
+	if v > MaxPhaseValue {
+		v = MaxPhaseValue
+	}
+	return v
+}
+
+// Phase returns the phase of the position.  The position is in the
+// endgame once the phase value falls to 8 or below or to 12 or below
+// with the queens traded.  The position is in the opening during the
+// first ten moves if little material has been traded.  Otherwise the
+// position is in the middlegame.
+func (pos *Position) Phase() Phase {
+	v := pos.PhaseValue()
+	queens := pos.board.bbWhiteQueen | pos.board.bbBlackQueen
+	switch {
+	case v <= endgameMaxPhase, queens == 0 && v <= queenlessMaxPhase:
+		return Endgame
+	case pos.moveCount <= openingMaxMoveCount && v >= openingMinPhase:
+		return Opening
+	}
+	return Middlegame
+}
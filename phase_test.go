@@ -0,0 +1,25 @@
+package chess
+
+import "testing"
+
+func TestPhase(t *testing.T) {
+	tests := []struct {
+		fen   string
+		phase Phase
+		value int
+	}{
+		{fen: startFEN, phase: Opening, value: MaxPhaseValue},
+		{fen: "r1bq1rk1/pp2bppp/2n1pn2/3p4/2PP4/2N2N2/PP2BPPP/R2QKB1R w KQ - 0 20", phase: Middlegame, value: 24},
+		{fen: "8/8/4k3/8/4P3/4K3/8/8 w - - 0 1", phase: Endgame, value: 0},
+		{fen: "3r2k1/5ppp/8/8/8/8/5PPP/2R3K1 w - - 0 30", phase: Endgame, value: 4},
+	}
+	for _, test := range tests {
+		pos := unsafeFEN(test.fen)
+		if pos.Phase() != test.phase {
+			t.Fatalf("expected %s to be %s but got %s", test.fen, test.phase, pos.Phase())
+		}
+		if pos.PhaseValue() != test.value {
+			t.Fatalf("expected %s to have phase value %d but got %d", test.fen, test.value, pos.PhaseValue())
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package chess
+
+// A PickedMove is a valid move yielded by a MovePicker along with its
+// algebraic notation.
+type PickedMove struct {
+	Move    *Move
+	SAN     string
+	Capture bool
+	Check   bool
+}
+
+// A MovePicker yields the valid moves of a position one at a time in
+// algebraic notation for building interfaces such as autocompletion.
+// Moves are generated on the first call to Next and each move's
+// notation is computed when it is first yielded and then cached, so
+// Reset can replay the moves without encoding them again.
+type MovePicker struct {
+	pos    *Position
+	moves  []*Move
+	picked []PickedMove
+	next   int
+}
+
+// MovePicker returns a MovePicker for the position's valid moves.
+func (pos *Position) MovePicker() *MovePicker {
+	return &MovePicker{pos: pos}
+}
+
+// Next returns the next move and true or false when every valid move
+// has been yielded.
+func (p *MovePicker) Next() (PickedMove, bool) {
+	if p.moves == nil {
+		p.moves = p.pos.validMovesRef()
+	}
+	if p.next >= len(p.moves) {
+		return PickedMove{}, false
+	}
+	if p.next == len(p.picked) {
+		m := p.moves[p.next]
+		p.picked = append(p.picked, PickedMove{
+			Move:    m,
+			SAN:     AlgebraicNotation{}.Encode(p.pos, m),
+			Capture: m.HasTag(Capture) || m.HasTag(EnPassant),
+			Check:   m.HasTag(Check),
+		})
+	}
+	pm := p.picked[p.next]
+	p.next++
+	return pm, true
+}
+
+// Reset restarts the picker from the first move.
+func (p *MovePicker) Reset() {
+	p.next = 0
+}
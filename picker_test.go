@@ -0,0 +1,35 @@
+package chess
+
+import "testing"
+
+func TestMovePicker(t *testing.T) {
+	pos := unsafeFEN("r3k3/8/8/3pP3/8/8/8/R3K3 w Qq d6 0 1")
+	expected := map[string]bool{}
+	for _, m := range pos.ValidMoves() {
+		expected[AlgebraicNotation{}.Encode(pos, m)] = true
+	}
+	picker := pos.MovePicker()
+	for pass := 0; pass < 2; pass++ {
+		seen := map[string]bool{}
+		for pm, ok := picker.Next(); ok; pm, ok = picker.Next() {
+			if !expected[pm.SAN] || seen[pm.SAN] {
+				t.Fatalf("unexpected or repeated move %s", pm.SAN)
+			}
+			seen[pm.SAN] = true
+			switch pm.SAN {
+			case "exd6":
+				if !pm.Capture || pm.Check {
+					t.Fatalf("expected exd6 to be a capture without check but got %+v", pm)
+				}
+			case "Rxa8+":
+				if !pm.Capture || !pm.Check {
+					t.Fatalf("expected Rxa8+ to be a capture with check but got %+v", pm)
+				}
+			}
+		}
+		if len(seen) != len(expected) {
+			t.Fatalf("expected %d moves but got %d", len(expected), len(seen))
+		}
+		picker.Reset()
+	}
+}
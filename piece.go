@@ -89,6 +89,25 @@ func (p PieceType) String() string {
 	return ""
 }
 
+// Name returns a display friendly name.
+func (p PieceType) Name() string {
+	switch p {
+	case King:
+		return "King"
+	case Queen:
+		return "Queen"
+	case Rook:
+		return "Rook"
+	case Bishop:
+		return "Bishop"
+	case Knight:
+		return "Knight"
+	case Pawn:
+		return "Pawn"
+	}
+	return "No Piece Type"
+}
+
 // Piece is a piece type with a color.
 type Piece int8
 
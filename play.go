@@ -0,0 +1,22 @@
+package chess
+
+// PlayGame plays a game between two move choosers, each called with
+// the game when it is their turn to move.  The game is created with
+// the given options and played until it is over.  If a chooser
+// returns nil or an invalid move the game is forfeited to the
+// opponent.  Games created with the MaxPlies option stop when the
+// limit is reached.
+func PlayGame(white, black func(*Game) *Move, opts ...func(*Game)) *Game {
+	g := NewGame(opts...)
+	for g.outcome == NoOutcome && !g.maxPliesReached {
+		choose := white
+		if g.pos.turn == Black {
+			choose = black
+		}
+		m := choose(g)
+		if m == nil || g.Move(m) != nil {
+			g.forfeit(g.pos.turn, Forfeit)
+		}
+	}
+	return g
+}
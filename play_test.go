@@ -0,0 +1,43 @@
+package chess
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func randomMover(seed int64) func(*Game) *Move {
+	r := rand.New(rand.NewSource(seed))
+	return func(g *Game) *Move {
+		moves := g.ValidMoves()
+		return moves[r.Intn(len(moves))]
+	}
+}
+
+func TestPlayGame(t *testing.T) {
+	g := PlayGame(randomMover(1), randomMover(2))
+	if g.Outcome() == NoOutcome || g.Method() == Forfeit {
+		t.Fatalf("expected game to be played to completion but got %s by %s", g.Outcome(), g.Method())
+	}
+	if err := g.Audit(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPlayGameForfeit(t *testing.T) {
+	white := func(g *Game) *Move { return &Move{s1: E2, s2: E5} }
+	g := PlayGame(white, randomMover(1))
+	if g.Outcome() != BlackWon || g.Method() != Forfeit || len(g.Moves()) != 0 {
+		t.Fatalf("expected white to forfeit but got %s by %s", g.Outcome(), g.Method())
+	}
+	g = PlayGame(randomMover(1), func(*Game) *Move { return nil })
+	if g.Outcome() != WhiteWon || g.Method() != Forfeit || len(g.Moves()) != 1 {
+		t.Fatalf("expected black to forfeit but got %s by %s", g.Outcome(), g.Method())
+	}
+}
+
+func TestPlayGameMaxPlies(t *testing.T) {
+	g := PlayGame(randomMover(1), randomMover(2), MaxPlies(10, nil))
+	if len(g.Moves()) != 10 || g.Method() == Forfeit {
+		t.Fatalf("expected game to stop after 10 plies but got %d plies and %s", len(g.Moves()), g.Method())
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 )
 
 // Side represents a side of the board.
@@ -25,6 +26,12 @@ type CastleRights string
 // CanCastle returns true if the given color and side combination
 // can castle, otherwise returns false.
 func (cr CastleRights) CanCastle(c Color, side Side) bool {
+	return strings.Contains(string(cr), castleRightChar(c, side))
+}
+
+// castleRightChar returns the FEN character of the castle right for
+// the color and side.
+func castleRightChar(c Color, side Side) string {
 	char := "k"
 	if side == QueenSide {
 		char = "q"
@@ -32,39 +39,129 @@ func (cr CastleRights) CanCastle(c Color, side Side) bool {
 	if c == White {
 		char = strings.ToUpper(char)
 	}
-	return strings.Contains(string(cr), char)
+	return char
 }
 
 // String implements the fmt.Stringer interface and returns
 // a FEN compatible string.  Ex. KQq
 func (cr CastleRights) String() string {
+	if cr == "" {
+		return "-"
+	}
 	return string(cr)
 }
 
+// castleRightChars are the castle rights characters for each color
+// and side in FEN order.
+var castleRightChars = [...]struct {
+	color Color
+	side  Side
+	char  string
+}{
+	{White, KingSide, "K"},
+	{White, QueenSide, "Q"},
+	{Black, KingSide, "k"},
+	{Black, QueenSide, "q"},
+}
+
+// rookFiles holds the files of the castling rooks indexed by color
+// and side.  Chess960 positions can start the rooks on any file while
+// a nil *rookFiles describes the a and h files of standard chess.
+type rookFiles [2][2]File
+
+// file returns the file of the rook that castles for the color to
+// the side.
+func (rf *rookFiles) file(c Color, side Side) File {
+	if rf == nil {
+		if side == KingSide {
+			return FileH
+		}
+		return FileA
+	}
+	return rf[c-White][side-KingSide]
+}
+
 // Position represents the state of the game without reguard
 // to its outcome.  Position is translatable to FEN notation.
 type Position struct {
 	board           *Board
 	turn            Color
 	castleRights    CastleRights
+	rookFiles       *rookFiles
 	enPassantSquare Square
 	halfMoveClock   int
 	moveCount       int
 	inCheck         bool
+	variant         Variant
 	validMoves      []*Move
 }
 
 const (
 	startFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+	emptyFEN = "8/8/8/8/8/8/8/8 w - - 0 1"
+)
+
+var (
+	startingPositionOnce sync.Once
+	startingPosition     *Position
 )
 
 // StartingPosition returns the starting position
 // rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1
+// The position is decoded and its valid moves are generated once.
+// Each call returns a copy sharing the valid moves.
 func StartingPosition() *Position {
-	pos, _ := decodeFEN(startFEN)
+	startingPositionOnce.Do(func() {
+		startingPosition, _ = decodeFEN(startFEN)
+		startingPosition.validMovesRef()
+	})
+	pos := startingPosition.copy()
+	pos.validMoves = startingPosition.validMoves
 	return pos
 }
 
+// EmptyPosition returns a position with no pieces on the board
+// 8/8/8/8/8/8/8/8 w - - 0 1
+// It is intended as a blank slate for position editors.  An empty
+// position isn't a legal chess position: both kings are missing so
+// check detection is skipped and the position has no valid moves.
+// Pieces should be placed, for example with NewBoard and NewPosition,
+// before the position is used to play a game.
+func EmptyPosition() *Position {
+	pos, _ := decodeFEN(emptyFEN)
+	return pos
+}
+
+// NewPosition returns a position with the given board, turn, and
+// castle rights such as "KQkq" or "-".  The position has no en
+// passant square and starts at move one.  An error is returned if
+// the turn or castle rights are invalid or if either color doesn't
+// have exactly one king.
+func NewPosition(b *Board, turn Color, cr CastleRights) (*Position, error) {
+	if turn != White && turn != Black {
+		return nil, fmt.Errorf("chess: invalid turn %s", turn)
+	}
+	if cr == "" {
+		cr = "-"
+	}
+	rights, err := formCastleRights(string(cr))
+	if err != nil {
+		return nil, err
+	}
+	pos := &Position{
+		board:           b.copy(),
+		turn:            turn,
+		castleRights:    rights,
+		enPassantSquare: NoSquare,
+		moveCount:       1,
+	}
+	if err := pos.hasLegalKings(); err != nil {
+		return nil, err
+	}
+	pos.inCheck = isInCheck(pos)
+	return pos, nil
+}
+
 // Update returns a new position resulting from the given move.
 // The move itself isn't validated, if validation is needed use
 // Game's Move method.  This method is more performant for bots that
@@ -88,20 +185,365 @@ func (pos *Position) Update(m *Move) *Position {
 		board:           b,
 		turn:            pos.turn.Other(),
 		castleRights:    ncr,
+		rookFiles:       pos.rookFiles,
 		enPassantSquare: pos.updateEnPassantSquare(m),
 		halfMoveClock:   halfMove,
 		moveCount:       moveCount,
 		inCheck:         m.HasTag(Check),
+		variant:         pos.variant,
 	}
 }
 
+// Undo holds the state needed to reverse a move made with
+// Position's MakeMove method.
+type Undo struct {
+	move            *Move
+	moved           Piece
+	captured        Piece
+	castleRook      Square
+	castleRights    CastleRights
+	enPassantSquare Square
+	halfMoveClock   int
+	moveCount       int
+	inCheck         bool
+	validMoves      []*Move
+}
+
+// MakeMove updates the position in place with the given move and
+// returns an Undo that restores it with UnmakeMove.  Like Update, the
+// move isn't validated.  Unlike Update, the position is mutated so it
+// must not be shared, for example with a Game.  Make and unmake avoid
+// allocating a new position for each move in deep searches.
+func (pos *Position) MakeMove(m *Move) *Undo {
+	u := &Undo{}
+	pos.makeMove(m, u)
+	return u
+}
+
+// makeMove is MakeMove with a caller provided Undo so that searches
+// can reuse them.
+func (pos *Position) makeMove(m *Move, u *Undo) {
+	*u = Undo{
+		move:            m,
+		moved:           pos.board.Piece(m.s1),
+		captured:        pos.board.Piece(m.s2),
+		castleRook:      NoSquare,
+		castleRights:    pos.castleRights,
+		enPassantSquare: pos.enPassantSquare,
+		halfMoveClock:   pos.halfMoveClock,
+		moveCount:       pos.moveCount,
+		inCheck:         pos.inCheck,
+		validMoves:      pos.validMoves,
+	}
+	if m.HasTag(KingSideCastle) || m.HasTag(QueenSideCastle) {
+		_, u.castleRook, _ = pos.board.castleRoute(m)
+		u.captured = NoPiece
+	} else if m.HasTag(EnPassant) {
+		u.captured = NewPiece(Pawn, pos.turn.Other())
+	}
+	if pos.turn == Black {
+		pos.moveCount++
+	}
+	if u.moved.Type() == Pawn || m.HasTag(Capture) {
+		pos.halfMoveClock = 0
+	} else {
+		pos.halfMoveClock++
+	}
+	pos.castleRights = pos.updateCastleRights(m)
+	pos.enPassantSquare = pos.updateEnPassantSquare(m)
+	pos.board.update(m)
+	pos.turn = pos.turn.Other()
+	pos.inCheck = m.HasTag(Check)
+	pos.validMoves = nil
+}
+
+// UnmakeMove reverses the move made with MakeMove that returned u.
+// Moves must be unmade in the reverse order they were made.
+func (pos *Position) UnmakeMove(u *Undo) {
+	pos.board.revert(u.move, u.moved, u.captured, u.castleRook)
+	pos.turn = pos.turn.Other()
+	pos.castleRights = u.castleRights
+	pos.enPassantSquare = u.enPassantSquare
+	pos.halfMoveClock = u.halfMoveClock
+	pos.moveCount = u.moveCount
+	pos.inCheck = u.inCheck
+	pos.validMoves = u.validMoves
+}
+
+// NullMove returns a new position in which the side to move passes
+// the turn without moving.  The en passant square is cleared and the
+// move counters advance as they would for a quiet move.  Null moves
+// aren't legal chess moves and are only meant for analysis.
+func (pos *Position) NullMove() *Position {
+	moveCount := pos.moveCount
+	if pos.turn == Black {
+		moveCount++
+	}
+	cp := &Position{
+		board:           pos.board.copy(),
+		turn:            pos.turn.Other(),
+		castleRights:    pos.castleRights,
+		rookFiles:       pos.rookFiles,
+		enPassantSquare: NoSquare,
+		halfMoveClock:   pos.halfMoveClock + 1,
+		moveCount:       moveCount,
+		variant:         pos.variant,
+	}
+	cp.inCheck = isInCheck(cp)
+	return cp
+}
+
 // ValidMoves returns a list of valid moves for the position.
 func (pos *Position) ValidMoves() []*Move {
+	return append([]*Move(nil), pos.validMovesRef()...)
+}
+
+// IsForced returns true if the position has exactly one valid move.
+func (pos *Position) IsForced() bool {
+	return len(pos.validMovesRef()) == 1
+}
+
+// ForcedMove returns the only valid move of the position.  The bool
+// result is false if there isn't exactly one valid move.
+func (pos *Position) ForcedMove() (*Move, bool) {
+	moves := pos.validMovesRef()
+	if len(moves) != 1 {
+		return nil, false
+	}
+	return moves[0], true
+}
+
+// ValidMovesInto appends the valid moves to buf and returns the
+// extended slice.  Engines can reuse the buffer, for example by
+// passing buf[:0] or a buffer from GetMoveBuffer, to avoid allocating
+// a new slice for every position they search.  Unlike ValidMoves the
+// generated moves aren't cached in the position.
+func (pos *Position) ValidMovesInto(buf []*Move) []*Move {
 	if pos.validMoves != nil {
-		return append([]*Move(nil), pos.validMoves...)
+		return append(buf, pos.validMoves...)
 	}
-	pos.validMoves = engine{}.CalcMoves(pos, false)
-	return append([]*Move(nil), pos.validMoves...)
+	return engine{}.appendMoves(buf, pos, false, nil)
+}
+
+var moveBufferPool = sync.Pool{
+	New: func() interface{} {
+		buf := make([]*Move, 0, 64)
+		return &buf
+	},
+}
+
+// GetMoveBuffer returns an empty move buffer from a sync.Pool for use
+// with ValidMovesInto.  Buffers are pointers so that returning them
+// to the pool doesn't allocate.
+func GetMoveBuffer() *[]*Move {
+	return moveBufferPool.Get().(*[]*Move)
+}
+
+// PutMoveBuffer empties the buffer and returns it to the pool used by
+// GetMoveBuffer.  The buffer's moves must not be used afterwards.
+func PutMoveBuffer(buf *[]*Move) {
+	clear(*buf)
+	*buf = (*buf)[:0]
+	moveBufferPool.Put(buf)
+}
+
+// ValidMovesFiltered returns the valid moves for which pred returns
+// true.  The predicate is applied during generation so that rejected
+// moves aren't collected.  If the position's valid moves have already
+// been generated they are filtered instead.
+func (pos *Position) ValidMovesFiltered(pred func(*Move) bool) []*Move {
+	if pos.validMoves == nil {
+		return engine{}.calcFilteredMoves(pos, false, pred)
+	}
+	moves := []*Move{}
+	for _, m := range pos.validMoves {
+		if pred(m) {
+			moves = append(moves, m)
+		}
+	}
+	return moves
+}
+
+// validMovesRef returns the cached valid moves without copying
+// them.  The returned slice must not be modified.
+func (pos *Position) validMovesRef() []*Move {
+	if pos.validMoves == nil {
+		pos.validMoves = engine{}.CalcMoves(pos, false)
+	}
+	return pos.validMoves
+}
+
+// Destinations returns the squares the piece on the given square
+// can legally move to.  Promotions to different piece types share
+// a single destination square.
+func (pos *Position) Destinations(from Square) []Square {
+	sqs := []Square{}
+	seen := map[Square]bool{}
+	for _, m := range pos.ValidMoves() {
+		if m.s1 != from || seen[m.s2] {
+			continue
+		}
+		seen[m.s2] = true
+		sqs = append(sqs, m.s2)
+	}
+	return sqs
+}
+
+// CastleMove returns the castling move for the color and side and
+// true if it is valid in the position.  Castling is only valid for
+// the color to move.
+func (pos *Position) CastleMove(c Color, s Side) (*Move, bool) {
+	if c != pos.turn {
+		return nil, false
+	}
+	tag := KingSideCastle
+	if s == QueenSide {
+		tag = QueenSideCastle
+	}
+	for _, m := range pos.validMovesRef() {
+		if m.HasTag(tag) {
+			return m, true
+		}
+	}
+	return nil, false
+}
+
+// CanCastle returns true if the color can castle to the side as
+// its next move.  Unlike CastleRights' CanCastle method it requires
+// that the path is clear and the king doesn't castle out of, through,
+// or into check.
+func (pos *Position) CanCastle(c Color, s Side) bool {
+	_, ok := pos.CastleMove(c, s)
+	return ok
+}
+
+// Successor is a position reached by playing Move.
+type Successor struct {
+	Move     *Move
+	Position *Position
+}
+
+// Successors returns the position resulting from each valid move
+// in the same order as ValidMoves.
+func (pos *Position) Successors() []Successor {
+	moves := pos.validMovesRef()
+	successors := make([]Successor, len(moves))
+	for i, m := range moves {
+		successors[i] = Successor{Move: m, Position: pos.Update(m)}
+	}
+	return successors
+}
+
+// AvailableCastles returns the castling moves that are valid in the
+// position.  Unlike the castling rights it is empty when the path is
+// blocked or the king would castle out of, through, or into check.
+func (pos *Position) AvailableCastles() []*Move {
+	moves := []*Move{}
+	for _, m := range pos.validMovesRef() {
+		if m.HasTag(KingSideCastle) || m.HasTag(QueenSideCastle) {
+			moves = append(moves, m)
+		}
+	}
+	return moves
+}
+
+// CheckingMoves returns the valid moves that put the opponent in
+// check.
+func (pos *Position) CheckingMoves() []*Move {
+	return pos.ValidMovesFiltered(func(m *Move) bool {
+		return m.HasTag(Check)
+	})
+}
+
+// MovesByPiece returns the valid moves for the position grouped
+// by the square of the piece being moved.
+func (pos *Position) MovesByPiece() map[Square][]*Move {
+	m := map[Square][]*Move{}
+	for _, move := range pos.ValidMoves() {
+		m[move.s1] = append(m[move.s1], move)
+	}
+	return m
+}
+
+// ControlMap returns the control of each square indexed by Square.
+// Each entry is the number of white pieces attacking the square
+// minus the number of black pieces attacking the square.
+func (pos *Position) ControlMap() [64]int {
+	var m [64]int
+	for sq := 0; sq < numOfSquaresInBoard; sq++ {
+		white := attackersBB(pos.board, Square(sq), White).Count()
+		black := attackersBB(pos.board, Square(sq), Black).Count()
+		m[sq] = white - black
+	}
+	return m
+}
+
+// Checkers returns the squares of the pieces giving check to the
+// side to move.
+func (pos *Position) Checkers() []Square {
+	kingSq := pos.board.whiteKingSq
+	if pos.turn == Black {
+		kingSq = pos.board.blackKingSq
+	}
+	sqs := []Square{}
+	if kingSq == NoSquare {
+		return sqs
+	}
+	bb := attackersBB(pos.board, kingSq, pos.turn.Other())
+	for sq := 0; bb != 0 && sq < numOfSquaresInBoard; sq++ {
+		if bb.Occupied(Square(sq)) {
+			sqs = append(sqs, Square(sq))
+		}
+	}
+	return sqs
+}
+
+// CheckCount returns the number of pieces giving check to the side
+// to move: 0, 1, or 2 for a double check.
+func (pos *Position) CheckCount() int {
+	return len(pos.Checkers())
+}
+
+// LeastValuableAttacker returns the square of the least valuable
+// piece of the given color attacking sq.  Only pieces on occupied
+// squares are considered and only occupied squares block sliding
+// pieces so removing captured pieces from occupied reveals x-ray
+// attackers.  It is a building block for static exchange evaluation.
+// The bool result is false if there is no attacker.
+func (pos *Position) LeastValuableAttacker(sq Square, by Color, occupied Bitboard) (Square, bool) {
+	attackers := attackersWithOccupancy(pos.board, sq, by, bitboard(occupied))
+	if attackers == 0 {
+		return NoSquare, false
+	}
+	for _, pt := range []PieceType{Pawn, Knight, Bishop, Rook, Queen, King} {
+		bb := attackers & pos.board.bbForPiece(NewPiece(pt, by))
+		for s := 0; bb != 0 && s < numOfSquaresInBoard; s++ {
+			if bb.Occupied(Square(s)) {
+				return Square(s), true
+			}
+		}
+	}
+	return NoSquare, false
+}
+
+// ResolvesCheck returns true if the moving side's king isn't in
+// check after the move.  The move only has to be pseudo legal, for
+// example a piece moving along its normal path, which makes it
+// useful for filtering candidate moves while the king is in check.
+func (pos *Position) ResolvesCheck(m *Move) bool {
+	cp := &Move{s1: m.s1, s2: m.s2, promo: m.promo, tags: m.tags & (KingSideCastle | QueenSideCastle)}
+	addTags(cp, pos)
+	return !cp.HasTag(inCheck)
+}
+
+// ExplainIllegal returns a human readable reason why the move isn't
+// valid in the position such as "not your turn" or "king would be in
+// check".  An empty string is returned if the move is valid.
+func (pos *Position) ExplainIllegal(m *Move) string {
+	if moveSlice(pos.ValidMoves()).find(m) != nil {
+		return ""
+	}
+	return engine{}.explainIllegal(pos, m)
 }
 
 // Status returns the position's status as one of the outcome methods.
@@ -110,6 +552,69 @@ func (pos *Position) Status() Method {
 	return engine{}.Status(pos)
 }
 
+// IsCheckmate returns true if the side to move is checkmated.
+func (pos *Position) IsCheckmate() bool {
+	return pos.Status() == Checkmate
+}
+
+// IsStalemate returns true if the side to move is stalemated.
+func (pos *Position) IsStalemate() bool {
+	return pos.Status() == Stalemate
+}
+
+// Validate returns an error if the position doesn't have exactly one
+// king per color, apart from White in Horde which has none, or is
+// already over.  Positions that are over include checkmate,
+// stalemate, positions won by the rules of their variant, and
+// standard positions with insufficient material for either side to
+// checkmate.  It is useful for asserting that a puzzle starts from a
+// live position.
+func (pos *Position) Validate() error {
+	if err := pos.hasLegalKings(); err != nil {
+		return err
+	}
+	switch pos.Status() {
+	case Checkmate:
+		return fmt.Errorf("chess: position %s is checkmate", pos)
+	case Stalemate:
+		return fmt.Errorf("chess: position %s is stalemate", pos)
+	case VariantEnd:
+		return fmt.Errorf("chess: position %s is over by the rules of %s", pos, pos.variant)
+	}
+	if pos.variant == Standard && !pos.board.hasSufficientMaterial() {
+		return fmt.Errorf("chess: position %s is dead due to insufficient material", pos)
+	}
+	return nil
+}
+
+// CanCheckmate returns true if the color could checkmate with any
+// cooperation from the opponent.  It is decided by material, which is
+// stricter than the insufficient material draw, and is intended for
+// the timeout rule: a player who runs out of time only loses if the
+// opponent can checkmate.  Pawns are assumed to be able to promote.
+func (pos *Position) CanCheckmate(c Color) bool {
+	return pos.board.canCheckmate(c)
+}
+
+// InsufficientMaterialReason returns a description of why neither
+// side has sufficient material to checkmate such as "king vs king",
+// "king and bishop vs king", "king and knight vs king", or
+// "same-colored bishops".  An empty string is returned if there is
+// sufficient material.
+func (pos *Position) InsufficientMaterialReason() string {
+	return pos.board.insufficientMaterialReason()
+}
+
+// SameGamePosition returns true if both positions have the same
+// piece placement, turn, castling rights, and en passant square when
+// an en passant capture is valid.
+// Unlike comparing FEN strings, the half move clock and move count
+// are ignored.  This is the comparison used for repetitions and
+// opening book keys.
+func (pos *Position) SameGamePosition(other *Position) bool {
+	return pos.samePosition(other)
+}
+
 // Board returns the position's board.
 func (pos *Position) Board() *Board {
 	return pos.board
@@ -120,11 +625,22 @@ func (pos *Position) Turn() Color {
 	return pos.turn
 }
 
+// Variant returns the rules the position is played by.
+func (pos *Position) Variant() Variant {
+	return pos.variant
+}
+
 // HalfMoveClock returns the half-move clock (50-rule).
 func (pos *Position) HalfMoveClock() int {
 	return pos.halfMoveClock
 }
 
+// MoveCount returns the full move number.  It starts at 1 and is
+// incremented after each of black's moves.
+func (pos *Position) MoveCount() int {
+	return pos.moveCount
+}
+
 // EnPassantSquare returns the en-passant square.
 func (pos *Position) EnPassantSquare() Square {
 	return pos.enPassantSquare
@@ -140,7 +656,7 @@ func (pos *Position) CastleRights() CastleRights {
 func (pos *Position) String() string {
 	b := pos.board.String()
 	t := pos.turn.String()
-	c := pos.castleRights.String()
+	c := pos.castleField()
 	sq := "-"
 	if pos.enPassantSquare != NoSquare {
 		sq = pos.enPassantSquare.String()
@@ -148,9 +664,31 @@ func (pos *Position) String() string {
 	return fmt.Sprintf("%s %s %s %s %d %d", b, t, c, sq, pos.halfMoveClock, pos.moveCount)
 }
 
-// Hash returns a unique hash of the position
+// RepetitionKey returns the key used to compare positions for
+// repetition.  It is made of the FEN piece placement, turn, castling
+// rights, and en passant square, which is only included if an en
+// passant capture is valid.  Positions are repetitions of each other
+// if and only if their keys are equal.
+func (pos *Position) RepetitionKey() string {
+	sq := "-"
+	if ep := pos.legalEnPassantSquare(); ep != NoSquare {
+		sq = ep.String()
+	}
+	return fmt.Sprintf("%s %s %s %s", pos.board, pos.turn, pos.castleField(), sq)
+}
+
+// Hash returns a hash of the position that covers the board, turn,
+// castling rights, and en passant square when an en passant capture
+// is valid.  Following the Polyglot convention the half move clock
+// and move count are excluded so transpositions hash equal regardless
+// of the move counters.
 func (pos *Position) Hash() [16]byte {
-	b, _ := pos.MarshalBinary()
+	cp := *pos
+	cp.enPassantSquare = pos.legalEnPassantSquare()
+	b, _ := cp.MarshalBinary()
+	// drop the half move clock (1 byte) and move count (2 bytes)
+	// which follow the board's 96 bytes
+	b = append(b[:96:96], b[99:]...)
 	return md5.Sum(b)
 }
 
@@ -169,6 +707,7 @@ func (pos *Position) UnmarshalText(text []byte) error {
 	}
 	pos.board = cp.board
 	pos.castleRights = cp.castleRights
+	pos.rookFiles = cp.rookFiles
 	pos.turn = cp.turn
 	pos.enPassantSquare = cp.enPassantSquare
 	pos.halfMoveClock = cp.halfMoveClock
@@ -282,32 +821,46 @@ func (pos *Position) UnmarshalBinary(data []byte) error {
 	return nil
 }
 
+// hasLegalKings returns an error unless each color has exactly one
+// king.  White has no king in Horde.
+func (pos *Position) hasLegalKings() error {
+	for _, c := range []Color{White, Black} {
+		n := pos.board.bbForPiece(NewPiece(King, c)).Count()
+		if pos.variant == Horde && c == White {
+			if n != 0 {
+				return fmt.Errorf("chess: horde position %s must not have a white king but has %d", pos, n)
+			}
+			continue
+		}
+		if n != 1 {
+			return fmt.Errorf("chess: position %s must have exactly one %s king but has %d", pos, strings.ToLower(c.Name()), n)
+		}
+	}
+	return nil
+}
+
 func (pos *Position) copy() *Position {
 	return &Position{
 		board:           pos.board.copy(),
 		turn:            pos.turn,
 		castleRights:    pos.castleRights,
+		rookFiles:       pos.rookFiles,
 		enPassantSquare: pos.enPassantSquare,
 		halfMoveClock:   pos.halfMoveClock,
 		moveCount:       pos.moveCount,
 		inCheck:         pos.inCheck,
+		variant:         pos.variant,
 	}
 }
 
 func (pos *Position) updateCastleRights(m *Move) CastleRights {
 	cr := string(pos.castleRights)
 	p := pos.board.Piece(m.s1)
-	if p == WhiteKing || m.s1 == H1 || m.s2 == H1 {
-		cr = strings.Replace(cr, "K", "", -1)
-	}
-	if p == WhiteKing || m.s1 == A1 || m.s2 == A1 {
-		cr = strings.Replace(cr, "Q", "", -1)
-	}
-	if p == BlackKing || m.s1 == H8 || m.s2 == H8 {
-		cr = strings.Replace(cr, "k", "", -1)
-	}
-	if p == BlackKing || m.s1 == A8 || m.s2 == A8 {
-		cr = strings.Replace(cr, "q", "", -1)
+	for _, r := range castleRightChars {
+		rook := pos.castleRookSquare(r.color, r.side)
+		if p == NewPiece(King, r.color) || m.s1 == rook || m.s2 == rook {
+			cr = strings.Replace(cr, r.char, "", -1)
+		}
 	}
 	if cr == "" {
 		cr = "-"
@@ -315,6 +868,45 @@ func (pos *Position) updateCastleRights(m *Move) CastleRights {
 	return CastleRights(cr)
 }
 
+// castleRookSquare returns the starting square of the rook that
+// castles for the color to the side.
+func (pos *Position) castleRookSquare(c Color, side Side) Square {
+	rank := Rank1
+	if c == Black {
+		rank = Rank8
+	}
+	return NewSquare(pos.rookFiles.file(c, side), rank)
+}
+
+// castleField returns the FEN castling field.  Chess960 rights
+// whose rook isn't the outermost rook on its side of the king are
+// written with the rook's file as in X-FEN.
+func (pos *Position) castleField() string {
+	if pos.rookFiles == nil {
+		return pos.castleRights.String()
+	}
+	s := ""
+	for _, r := range castleRightChars {
+		if !pos.castleRights.CanCastle(r.color, r.side) {
+			continue
+		}
+		file := pos.rookFiles.file(r.color, r.side)
+		if f, ok := pos.board.outermostRook(r.color, r.side); ok && f == file {
+			s += r.char
+			continue
+		}
+		char := file.String()
+		if r.color == White {
+			char = strings.ToUpper(char)
+		}
+		s += char
+	}
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
 func (pos *Position) updateEnPassantSquare(m *Move) Square {
 	p := pos.board.Piece(m.s1)
 	if p.Type() != Pawn {
@@ -332,9 +924,25 @@ func (pos *Position) updateEnPassantSquare(m *Move) Square {
 	return NoSquare
 }
 
+// samePosition returns true if the positions are the same for
+// repetition purposes.  It matches the comparison of RepetitionKey.
 func (pos *Position) samePosition(pos2 *Position) bool {
-	return pos.board.String() == pos2.board.String() &&
+	return pos.board.samePieces(pos2.board) &&
 		pos.turn == pos2.turn &&
-		pos.castleRights.String() == pos2.castleRights.String() &&
-		pos.enPassantSquare == pos2.enPassantSquare
+		pos.castleField() == pos2.castleField() &&
+		pos.legalEnPassantSquare() == pos2.legalEnPassantSquare()
+}
+
+// legalEnPassantSquare returns the en passant square if an en
+// passant capture is valid in the position or NoSquare otherwise.
+func (pos *Position) legalEnPassantSquare() Square {
+	if pos.enPassantSquare == NoSquare {
+		return NoSquare
+	}
+	for _, m := range pos.validMovesRef() {
+		if m.HasTag(EnPassant) {
+			return pos.enPassantSquare
+		}
+	}
+	return NoSquare
 }
@@ -1,6 +1,9 @@
 package chess
 
 import (
+	"fmt"
+	"math/rand"
+	"strings"
 	"testing"
 )
 
@@ -23,3 +26,427 @@ func TestPositionBinary(t *testing.T) {
 		}
 	}
 }
+
+func TestEmptyPosition(t *testing.T) {
+	pos := EmptyPosition()
+	if len(pos.Board().SquareMap()) != 0 {
+		t.Fatalf("expected empty board but got %s", pos.Board())
+	}
+	if len(pos.ValidMoves()) != 0 {
+		t.Fatalf("expected no valid moves but got %d", len(pos.ValidMoves()))
+	}
+	m := pos.Board().SquareMap()
+	m[E1] = WhiteKing
+	m[E8] = BlackKing
+	m[A1] = WhiteRook
+	placed, err := NewPosition(NewBoard(m), White, "Q")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "4k3/8/8/8/8/8/8/R3K3 w Q - 0 1"
+	if placed.String() != expected {
+		t.Fatalf("expected %s but got %s", expected, placed.String())
+	}
+	if _, ok := placed.CastleMove(White, QueenSide); !ok {
+		t.Fatal("expected queen side castling after placing pieces")
+	}
+	if _, err := NewPosition(NewBoard(m), NoColor, "-"); err == nil {
+		t.Fatal("expected an error for a position without a turn")
+	}
+	if _, err := NewPosition(NewBoard(m), Black, "KX"); err == nil {
+		t.Fatal("expected an error for invalid castle rights")
+	}
+	delete(m, E1)
+	if _, err := NewPosition(NewBoard(m), White, "-"); err == nil || !strings.Contains(err.Error(), "one white king but has 0") {
+		t.Fatalf("expected an error for a position without a white king but got %v", err)
+	}
+	m[E1] = WhiteKing
+	m[H8] = BlackKing
+	if _, err := NewPosition(NewBoard(m), White, "-"); err == nil || !strings.Contains(err.Error(), "one black king but has 2") {
+		t.Fatalf("expected an error for a position with two black kings but got %v", err)
+	}
+}
+
+func TestPositionValidate(t *testing.T) {
+	tests := []struct {
+		fen string
+		msg string
+	}{
+		{fen: "k1K5/8/1Q6/8/8/8/8/8 b - - 0 1", msg: "stalemate"},
+		{fen: "rn1qkbnr/pbpp1Qpp/1p6/4p3/2B1P3/8/PPPP1PPP/RNB1K1NR b KQkq - 0 1", msg: "checkmate"},
+		{fen: "8/2k5/8/8/8/3K1N2/8/8 w - - 1 1", msg: "insufficient material"},
+		{fen: startFEN, msg: ""},
+	}
+	for _, test := range tests {
+		pos := &Position{}
+		if err := pos.UnmarshalText([]byte(test.fen)); err != nil {
+			t.Fatal(err)
+		}
+		err := pos.Validate()
+		if test.msg == "" {
+			if err != nil {
+				t.Fatalf("expected %s to be valid but got %s", test.fen, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), test.msg) {
+			t.Fatalf("expected %s to return error containing %q but got %v", test.fen, test.msg, err)
+		}
+	}
+	if !unsafeFEN("k1K5/8/1Q6/8/8/8/8/8 b - - 0 1").IsStalemate() {
+		t.Fatal("expected position to be stalemate")
+	}
+}
+
+func TestExplainIllegal(t *testing.T) {
+	tests := []struct {
+		fen    string
+		m      *Move
+		reason string
+	}{
+		// knight pinned to king by bishop
+		{fen: "4k3/8/8/b7/8/8/3N4/4K3 w - - 0 1", m: &Move{s1: D2, s2: F3}, reason: "king would be in check"},
+		// king in check from rook and move ignores it
+		{fen: "4r1k1/8/8/8/8/8/P7/4K3 w - - 0 1", m: &Move{s1: A2, s2: A3}, reason: "move does not resolve check"},
+		{fen: startFEN, m: &Move{s1: E7, s2: E5}, reason: "not your turn"},
+		{fen: startFEN, m: &Move{s1: D1, s2: D3}, reason: "path from d1 to d3 is blocked"},
+		{fen: startFEN, m: &Move{s1: G1, s2: G3}, reason: "knight on g1 cannot move to g3"},
+		{fen: "4k3/8/8/8/8/8/8/R3K2r w Q - 0 1", m: &Move{s1: E1, s2: G1}, reason: "castling rights have been lost"},
+		{fen: "rk4r1/8/8/8/8/8/8/RK4R1 w GAga - 0 1", m: &Move{s1: B1, s2: G1}, reason: "cannot castle through check"},
+		{fen: "4k3/8/8/8/8/8/8/4K3 w - - 0 1", m: &Move{s1: E3, s2: E4}, reason: "no piece on e3"},
+		{fen: startFEN, m: &Move{s1: E2, s2: E4}, reason: ""},
+	}
+	for _, test := range tests {
+		pos := &Position{}
+		if err := pos.UnmarshalText([]byte(test.fen)); err != nil {
+			t.Fatal(err)
+		}
+		reason := pos.ExplainIllegal(test.m)
+		if reason != test.reason {
+			t.Fatalf("expected move %s in %s to be explained as %q but got %q", test.m, test.fen, test.reason, reason)
+		}
+	}
+}
+
+func TestDestinations(t *testing.T) {
+	pos := unsafeFEN("4k3/8/8/8/8/3p4/4P3/4K3 w - - 0 1")
+	dests := pos.Destinations(E2)
+	expected := map[Square]bool{E3: true, E4: true, D3: true}
+	if len(dests) != len(expected) {
+		t.Fatalf("expected %d destinations but got %v", len(expected), dests)
+	}
+	for _, sq := range dests {
+		if !expected[sq] {
+			t.Fatalf("unexpected destination %s", sq)
+		}
+	}
+	pos = unsafeFEN("3rk3/4P3/8/8/8/8/8/4K3 w - - 0 1")
+	if dests := pos.Destinations(E7); len(dests) != 1 || dests[0] != D8 {
+		t.Fatalf("expected promotions to share destination %s but got %v", D8, dests)
+	}
+}
+
+func TestMovesByPiece(t *testing.T) {
+	moves := StartingPosition().MovesByPiece()
+	if len(moves) != 10 {
+		t.Fatalf("expected 10 pieces with moves but got %d", len(moves))
+	}
+	knight := moves[B1]
+	expected := map[Square]bool{A3: true, C3: true}
+	if len(knight) != len(expected) {
+		t.Fatalf("expected knight on b1 to have %d moves but got %v", len(expected), knight)
+	}
+	for _, m := range knight {
+		if m.S1() != B1 || !expected[m.S2()] {
+			t.Fatalf("unexpected knight move %s", m)
+		}
+	}
+}
+
+func TestValidMovesFiltered(t *testing.T) {
+	fen := "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1"
+	isCapture := func(m *Move) bool { return m.HasTag(Capture) }
+	captures := unsafeFEN(fen).ValidMovesFiltered(isCapture)
+	expected := []*Move{}
+	for _, m := range unsafeFEN(fen).ValidMoves() {
+		if isCapture(m) {
+			expected = append(expected, m)
+		}
+	}
+	if len(captures) == 0 || len(captures) != len(expected) {
+		t.Fatalf("expected %d captures but got %d", len(expected), len(captures))
+	}
+	for i := range captures {
+		if captures[i].String() != expected[i].String() {
+			t.Fatalf("expected capture %s but got %s", expected[i], captures[i])
+		}
+	}
+	pos := unsafeFEN(fen)
+	pos.ValidMoves()
+	if cached := pos.ValidMovesFiltered(isCapture); len(cached) != len(expected) {
+		t.Fatalf("expected %d cached captures but got %d", len(expected), len(cached))
+	}
+}
+
+func TestSameGamePosition(t *testing.T) {
+	a := unsafeFEN("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3")
+	b := unsafeFEN("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 6 5")
+	if !a.SameGamePosition(b) {
+		t.Fatal("expected positions differing only in counters to be the same")
+	}
+	c := unsafeFEN("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w Qkq - 2 3")
+	if a.SameGamePosition(c) {
+		t.Fatal("expected positions with different castling rights to differ")
+	}
+}
+
+func TestPositionCastleMove(t *testing.T) {
+	start := StartingPosition()
+	for _, side := range []Side{KingSide, QueenSide} {
+		if start.CanCastle(White, side) {
+			t.Fatalf("expected castling to be blocked in the starting position")
+		}
+	}
+	pos := unsafeFEN("r3k2r/pppppppp/8/8/8/8/PPPPPPPP/R3K2R w KQkq - 0 1")
+	tests := []struct {
+		side Side
+		s2   Square
+	}{
+		{KingSide, G1},
+		{QueenSide, C1},
+	}
+	for _, test := range tests {
+		m, ok := pos.CastleMove(White, test.side)
+		if !ok || m.S1() != E1 || m.S2() != test.s2 {
+			t.Fatalf("expected castle to %s but got %v", test.s2, m)
+		}
+		if pos.CanCastle(Black, test.side) {
+			t.Fatal("expected black not to castle on white's turn")
+		}
+	}
+}
+
+func TestControlMap(t *testing.T) {
+	m := StartingPosition().ControlMap()
+	expected := map[Square]int{
+		C3: 3, D3: 2, E3: 2, F3: 3,
+		D4: 0, E4: 0, D5: 0, E5: 0,
+		C6: -3, D6: -2, E6: -2, F6: -3,
+	}
+	for sq, v := range expected {
+		if m[sq] != v {
+			t.Fatalf("expected control of %s to be %d but got %d", sq, v, m[sq])
+		}
+	}
+}
+
+func TestInsufficientMaterialReason(t *testing.T) {
+	tests := map[string]string{
+		"8/2k5/8/8/8/3K4/8/8 w - - 1 1":       "king vs king",
+		"8/2k5/8/8/8/3K1N2/8/8 w - - 1 1":     "king and knight vs king",
+		"8/2k5/8/8/8/3K1B2/8/8 w - - 1 1":     "king and bishop vs king",
+		"4b3/2k5/2b5/8/8/3K1B2/8/8 w - - 1 1": "same-colored bishops",
+		"8/2k5/8/8/8/3KBB2/8/8 w - - 1 1":     "",
+	}
+	for fen, expected := range tests {
+		reason := unsafeFEN(fen).InsufficientMaterialReason()
+		if reason != expected {
+			t.Fatalf("expected %s to have reason %q but got %q", fen, expected, reason)
+		}
+	}
+}
+
+func TestPositionStringReparses(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	g := NewGame()
+	for i := 0; i < 40 && g.Outcome() == NoOutcome; i++ {
+		moves := g.ValidMoves()
+		if err := g.Move(moves[r.Intn(len(moves))]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, pos := range g.Positions() {
+		fen := pos.String()
+		cp, err := decodeFEN(fen)
+		if err != nil {
+			t.Fatalf("expected %s to re-parse but got %s", fen, err)
+		}
+		if cp.String() != fen || !cp.samePosition(pos) || cp.inCheck != pos.inCheck {
+			t.Fatalf("expected %s to re-parse to an equal position but got %s", fen, cp)
+		}
+	}
+	pos := &Position{board: EmptyPosition().board, turn: White, enPassantSquare: NoSquare, moveCount: 1}
+	if _, err := decodeFEN(pos.String()); err != nil {
+		t.Fatalf("expected position without castle rights to re-parse but got %s", err)
+	}
+}
+
+func TestUpdateCastleRights(t *testing.T) {
+	tests := []struct {
+		fen      string
+		m        *Move
+		expected CastleRights
+	}{
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", &Move{s1: E1, s2: E2}, "kq"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", &Move{s1: H1, s2: H2}, "Qkq"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", &Move{s1: A8, s2: A7}, "KQk"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", &Move{s1: A1, s2: A8}, "Kk"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", &Move{s1: H8, s2: H1}, "Qq"},
+	}
+	for _, test := range tests {
+		pos := unsafeFEN(test.fen).Update(test.m)
+		if pos.CastleRights() != test.expected {
+			t.Fatalf("expected %s after %s from %s but got %s", test.expected, test.m, test.fen, pos.CastleRights())
+		}
+	}
+	// a rook moving out and back doesn't repeat the original position
+	start := unsafeFEN("r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1")
+	pos := start.Update(&Move{s1: H1, s2: H2}).Update(&Move{s1: H8, s2: H7})
+	pos = pos.Update(&Move{s1: H2, s2: H1}).Update(&Move{s1: H7, s2: H8})
+	if pos.Board().String() != start.Board().String() {
+		t.Fatalf("expected the starting board but got %s", pos.Board())
+	}
+	if pos.SameGamePosition(start) {
+		t.Fatal("expected lost castling rights to prevent a repetition")
+	}
+}
+
+func TestLeastValuableAttacker(t *testing.T) {
+	// e5 is defended by the d6 pawn and the e7 queen with a rook
+	// behind the queen
+	pos := unsafeFEN("4r1k1/4q3/3p4/4N3/8/8/8/4R1K1 w - - 0 1")
+	occ := pos.Board().Occupied()
+	sq, ok := pos.LeastValuableAttacker(E5, Black, occ)
+	if !ok || sq != D6 {
+		t.Fatalf("expected the d6 pawn to attack first but got %s", sq)
+	}
+	occ = occ.Without(D6)
+	if sq, ok = pos.LeastValuableAttacker(E5, Black, occ); !ok || sq != E7 {
+		t.Fatalf("expected the e7 queen to attack next but got %s", sq)
+	}
+	occ = occ.Without(E7)
+	if sq, ok = pos.LeastValuableAttacker(E5, Black, occ); !ok || sq != E8 {
+		t.Fatalf("expected the e8 rook x-ray to attack next but got %s", sq)
+	}
+	occ = occ.Without(E8)
+	if sq, ok = pos.LeastValuableAttacker(E5, Black, occ); ok {
+		t.Fatalf("expected no attackers but got %s", sq)
+	}
+	if sq, ok = pos.LeastValuableAttacker(E5, White, pos.Board().Occupied()); !ok || sq != E1 {
+		t.Fatalf("expected the e1 rook to defend but got %s", sq)
+	}
+}
+
+func TestForcedMove(t *testing.T) {
+	// the bishop covers g7 so the king can only escape to g8
+	pos := unsafeFEN("7k/8/8/8/8/8/1B6/K6R b - - 0 1")
+	m, ok := pos.ForcedMove()
+	if !pos.IsForced() || !ok || m.S1() != H8 || m.S2() != G8 {
+		t.Fatalf("expected forced move h8g8 but got %v", m)
+	}
+	if pos := unsafeFEN("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"); pos.IsForced() {
+		t.Fatal("expected the starting position not to be forced")
+	}
+	if _, ok := unsafeFEN("7k/5Q2/6K1/8/8/8/8/8 b - - 0 1").ForcedMove(); ok {
+		t.Fatal("expected no forced move in stalemate")
+	}
+}
+
+func TestCheckingMoves(t *testing.T) {
+	pos := unsafeFEN("4k3/8/8/1N6/8/8/8/4K3 w - - 0 1")
+	moves := pos.CheckingMoves()
+	SortMoves(moves)
+	if s := fmt.Sprint(moves); s != "[b5d6 b5c7]" {
+		t.Fatalf("expected checking moves [b5d6 b5c7] but got %s", s)
+	}
+	pos.ValidMoves()
+	if cached := pos.CheckingMoves(); len(cached) != 2 {
+		t.Fatalf("expected 2 checking moves from cached moves but got %v", cached)
+	}
+}
+
+func TestAvailableCastles(t *testing.T) {
+	pos := unsafeFEN("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+	if moves := pos.AvailableCastles(); len(moves) != 0 {
+		t.Fatalf("expected blocked castles to be unavailable but got %v", moves)
+	}
+	pos = unsafeFEN("r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1")
+	if moves := pos.AvailableCastles(); len(moves) != 2 {
+		t.Fatalf("expected both castles to be available but got %v", moves)
+	}
+	// the bishop on a6 attacks f1 so only queen side castling remains
+	pos = unsafeFEN("r3k2r/8/b7/8/8/8/8/R3K2R w KQkq - 0 1")
+	moves := pos.AvailableCastles()
+	if len(moves) != 1 || !moves[0].HasTag(QueenSideCastle) {
+		t.Fatalf("expected only queen side castling but got %v", moves)
+	}
+}
+
+func TestResolvesCheck(t *testing.T) {
+	// the rook on e8 checks the king and the bishop can block on e2
+	pos := unsafeFEN("4r1k1/8/8/8/8/8/8/3BK3 w - - 0 1")
+	if !pos.ResolvesCheck(&Move{s1: D1, s2: E2}) {
+		t.Fatal("expected Be2 to block the check")
+	}
+	if pos.ResolvesCheck(&Move{s1: D1, s2: C2}) {
+		t.Fatal("expected Bc2 not to resolve the check")
+	}
+	if !pos.ResolvesCheck(&Move{s1: E1, s2: F2}) {
+		t.Fatal("expected Kf2 to escape the check")
+	}
+}
+
+func TestSuccessors(t *testing.T) {
+	pos := StartingPosition()
+	successors := pos.Successors()
+	if len(successors) != 20 {
+		t.Fatalf("expected 20 successors but got %d", len(successors))
+	}
+	for _, s := range successors {
+		if s.Position.String() != pos.Update(s.Move).String() {
+			t.Fatalf("expected successor of %s to be %s but got %s", s.Move, pos.Update(s.Move), s.Position)
+		}
+	}
+}
+
+func TestCanCheckmate(t *testing.T) {
+	tests := []struct {
+		fen      string
+		color    Color
+		expected bool
+	}{
+		{"4k3/8/8/8/8/8/8/4KN2 w - - 0 1", White, false},
+		{"4k3/4p3/8/8/8/8/8/4KN2 w - - 0 1", White, true},
+		{"4k3/4p3/8/8/8/8/8/4KN2 w - - 0 1", Black, true},
+		{"4k3/8/8/8/8/8/8/4K3 w - - 0 1", White, false},
+		{"4kq2/8/8/8/8/8/8/4KN2 w - - 0 1", White, false},
+		{"4k3/8/8/8/8/8/8/3NKN2 w - - 0 1", White, true},
+		{"4k3/8/8/8/8/8/8/2B1KB2 w - - 0 1", White, true},
+		{"4k3/8/8/8/8/8/8/2B1K1B1 w - - 0 1", White, false},
+		{"2b1k3/8/8/8/8/8/8/2B1K3 w - - 0 1", White, true},
+		{"4kb2/8/8/8/8/8/8/2B1K3 w - - 0 1", White, false},
+		{"4k3/8/8/8/8/8/8/4K2R w - - 0 1", White, true},
+	}
+	for _, test := range tests {
+		if got := unsafeFEN(test.fen).CanCheckmate(test.color); got != test.expected {
+			t.Fatalf("expected CanCheckmate(%s) to be %t for %s", test.color, test.expected, test.fen)
+		}
+	}
+}
+
+func TestCheckCount(t *testing.T) {
+	tests := []struct {
+		fen      string
+		expected int
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", 0},
+		{"4k3/8/8/8/8/8/8/4RK2 b - - 0 1", 1},
+		// the knight on d6 and the rook on e1 both check the king
+		{"4k3/8/3N4/8/8/8/8/4RK2 b - - 0 1", 2},
+	}
+	for _, test := range tests {
+		if n := unsafeFEN(test.fen).CheckCount(); n != test.expected {
+			t.Fatalf("expected %d checks for %s but got %d", test.expected, test.fen, n)
+		}
+	}
+}
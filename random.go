@@ -0,0 +1,35 @@
+package chess
+
+import (
+	"errors"
+	"math/rand"
+)
+
+// RandomGame returns a game of uniformly random valid moves played
+// from the starting position until the game is over or maxPlies moves
+// have been played.  Games generated with the same seed are identical
+// which makes them useful for reproducible tests.
+func RandomGame(seed int64, maxPlies int) *Game {
+	r := rand.New(rand.NewSource(seed))
+	g := NewGame()
+	for ply := 0; ply < maxPlies && g.Outcome() == NoOutcome; ply++ {
+		if _, err := g.PlayRandomMove(r); err != nil {
+			panic(err)
+		}
+	}
+	return g
+}
+
+// PlayRandomMove plays a uniformly random valid move chosen with rng
+// and returns it.  An error is returned if the game is already over.
+func (g *Game) PlayRandomMove(rng *rand.Rand) (*Move, error) {
+	moves := g.pos.validMovesRef()
+	if g.outcome != NoOutcome || len(moves) == 0 {
+		return nil, errors.New("chess: can't play a random move in a completed game")
+	}
+	m := moves[rng.Intn(len(moves))]
+	if err := g.Move(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
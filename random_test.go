@@ -0,0 +1,44 @@
+package chess
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestRandomGame(t *testing.T) {
+	for seed := int64(0); seed < 5; seed++ {
+		a := RandomGame(seed, 200)
+		b := RandomGame(seed, 200)
+		if a.String() != b.String() {
+			t.Fatalf("expected seed %d to produce the same game but got\n%s\n%s", seed, a, b)
+		}
+		if len(a.Moves()) > 200 {
+			t.Fatalf("expected at most 200 moves but got %d", len(a.Moves()))
+		}
+		if a.Outcome() == NoOutcome && len(a.Moves()) != 200 {
+			t.Fatalf("expected unfinished game to reach the ply cap but got %d moves", len(a.Moves()))
+		}
+	}
+	if RandomGame(1, 40).String() == RandomGame(2, 40).String() {
+		t.Fatal("expected different seeds to produce different games")
+	}
+}
+
+func TestPlayRandomMove(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	for i := 0; i < 3; i++ {
+		g := NewGame()
+		for g.Outcome() == NoOutcome {
+			m, err := g.PlayRandomMove(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if last := g.Moves()[len(g.Moves())-1]; last != m {
+				t.Fatalf("expected returned move %s to be the last move played but got %s", m, last)
+			}
+		}
+		if _, err := g.PlayRandomMove(r); err == nil {
+			t.Fatal("expected error playing a random move in a completed game")
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package chess
+
+import (
+	"strings"
+	"testing"
+)
+
+type repetitionTest struct {
+	name      string
+	fen       string
+	moves     []string
+	threefold int // first ply with a threefold repetition or 0 for none
+	fivefold  int // ply of the automatic fivefold draw or 0 for none
+}
+
+var repetitionTests = []repetitionTest{
+	{
+		name:      "king side knight shuffle",
+		moves:     repeatMoves("Nf3 Nf6 Ng1 Ng8", 4),
+		threefold: 8,
+		fivefold:  16,
+	},
+	{
+		name:      "queen side knight shuffle",
+		moves:     repeatMoves("Nc3 Nc6 Nb1 Nb8", 2),
+		threefold: 8,
+	},
+	{
+		name:      "repetition after pawn moves",
+		moves:     append([]string{"e3", "e6"}, repeatMoves("Nf3 Nf6 Ng1 Ng8", 2)...),
+		threefold: 10,
+	},
+	{
+		name:      "pawn moves interrupt the repetition",
+		moves:     strings.Fields("Nf3 Nf6 Ng1 Ng8 e3 e6 Nf3 Nf6 Ng1 Ng8"),
+		threefold: 0,
+	},
+	{
+		name:      "queen triangulation",
+		fen:       "4k3/8/8/8/8/8/8/Q3K3 w - - 0 1",
+		moves:     repeatMoves("Qa2 Kd8 Qb1 Ke8 Qa1 Kd8 Qa2 Ke8 Qb1 Kd8 Qa1 Ke8", 2),
+		threefold: 24,
+	},
+	// the starting board reappears at ply 8 for the third time
+	// but without king side castling rights so it isn't counted
+	{
+		name:      "lost castling rights make a new position",
+		fen:       "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1",
+		moves:     repeatMoves("Rh2 Rh7 Rh1 Rh8", 3),
+		threefold: 10,
+	},
+	{
+		name:      "shuffle to fivefold from a fen",
+		fen:       "4k3/8/8/8/8/8/8/4K2R w - - 0 1",
+		moves:     repeatMoves("Rh2 Kd7 Rh1 Ke8", 4),
+		threefold: 8,
+		fivefold:  16,
+	},
+}
+
+func repeatMoves(moves string, n int) []string {
+	return strings.Fields(strings.Repeat(moves+" ", n))
+}
+
+func TestRepetitionScenarios(t *testing.T) {
+	for _, test := range repetitionTests {
+		g := NewGame()
+		if test.fen != "" {
+			fen, err := FEN(test.fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			g = NewGame(fen)
+		}
+		for i, m := range test.moves {
+			ply := i + 1
+			if err := g.MoveStr(m); err != nil {
+				t.Fatalf("%s: %s", test.name, err)
+			}
+			// later plies may or may not be on a repeated position
+			if test.threefold == 0 || ply <= test.threefold {
+				expected := ply == test.threefold
+				if g.HasThreefoldRepetition() != expected {
+					t.Fatalf("%s: expected threefold repetition %t at ply %d", test.name, expected, ply)
+				}
+				if hasMethod(g.EligibleDraws(), ThreefoldRepetition) != expected {
+					t.Fatalf("%s: expected threefold draw eligibility %t at ply %d", test.name, expected, ply)
+				}
+			}
+			fivefold := g.Method() == FivefoldRepetition
+			if fivefold != (ply == test.fivefold) {
+				t.Fatalf("%s: expected fivefold draw %t at ply %d", test.name, !fivefold, ply)
+			}
+			if fivefold {
+				break
+			}
+		}
+		if test.threefold != 0 && test.fivefold == 0 {
+			if err := g.Draw(ThreefoldRepetition); err != nil {
+				t.Fatalf("%s: %s", test.name, err)
+			}
+		}
+	}
+}
+
+func hasMethod(methods []Method, method Method) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRepetitionKey(t *testing.T) {
+	// the en passant square after e4 doesn't matter since no black
+	// pawn can capture so the position repeats after the knights return
+	g := NewGame()
+	for _, m := range strings.Fields("e4 Nf6 Nf3 Ng8 Ng1 Nf6 Nf3 Ng8 Ng1") {
+		if err := g.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	positions := g.Positions()
+	first, last := positions[1], positions[len(positions)-1]
+	if first.EnPassantSquare() != E3 {
+		t.Fatalf("expected en passant square e3 but got %s", first.EnPassantSquare())
+	}
+	if first.RepetitionKey() != last.RepetitionKey() {
+		t.Fatalf("expected repetition keys to match but got %s and %s", first.RepetitionKey(), last.RepetitionKey())
+	}
+	if !g.HasThreefoldRepetition() {
+		t.Fatal("expected a threefold repetition")
+	}
+	// a capturable en passant pawn makes a different position
+	pos := unsafeFEN("4k3/8/8/8/3pP3/8/8/4K3 b - e3 0 1")
+	if key := pos.RepetitionKey(); key != "4k3/8/8/8/3pP3/8/8/4K3 b - e3" {
+		t.Fatalf("expected key to include the en passant square but got %s", key)
+	}
+}
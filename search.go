@@ -0,0 +1,97 @@
+package chess
+
+import "math"
+
+// MateScore is the score Searcher's Negamax gives to being
+// checkmated at the root.  Mates further from the root score closer
+// to zero by one point per ply so shorter mates are preferred.
+const MateScore = 1 << 20
+
+// Searcher runs searches that reuse move buffers and undo records
+// across nodes with MakeMove and UnmakeMove instead of allocating a
+// new position for every move.  A Searcher isn't safe for concurrent
+// use but can be reused for any number of searches.
+type Searcher struct {
+	moves [][]*Move
+	undos []Undo
+}
+
+// NewSearcher returns a Searcher with no preallocated state.
+func NewSearcher() *Searcher {
+	return &Searcher{}
+}
+
+// Perft returns the number of leaf nodes of the move tree of the
+// given depth.  The position isn't modified.
+func (s *Searcher) Perft(pos *Position, depth int) int {
+	if depth < 1 {
+		return 1
+	}
+	return s.perft(pos.copy(), depth, 0)
+}
+
+func (s *Searcher) perft(pos *Position, depth, ply int) int {
+	moves := s.validMoves(pos, ply)
+	if depth == 1 {
+		return len(moves)
+	}
+	nodes := 0
+	for _, m := range moves {
+		pos.makeMove(m, &s.undos[ply])
+		nodes += s.perft(pos, depth-1, ply+1)
+		pos.UnmakeMove(&s.undos[ply])
+	}
+	return nodes
+}
+
+// Negamax searches the position to the given depth with alpha-beta
+// pruning and returns the score and best move for the side to move.
+// The eval function scores leaf positions from the perspective of
+// their side to move.  Checkmate scores -MateScore plus the ply and
+// stalemate scores zero.  Draws by repetition and the fifty move rule
+// aren't detected.  The best move is nil if the position has no valid
+// moves.  The position isn't modified.
+func (s *Searcher) Negamax(pos *Position, depth int, eval func(*Position) int) (int, *Move) {
+	return s.negamax(pos.copy(), depth, 0, math.MinInt32, math.MaxInt32, eval)
+}
+
+func (s *Searcher) negamax(pos *Position, depth, ply, alpha, beta int, eval func(*Position) int) (int, *Move) {
+	moves := s.validMoves(pos, ply)
+	if len(moves) == 0 {
+		if pos.inCheck {
+			return -MateScore + ply, nil
+		}
+		return 0, nil
+	}
+	if depth <= 0 {
+		return eval(pos), nil
+	}
+	best, bestScore := moves[0], math.MinInt32
+	for _, m := range moves {
+		pos.makeMove(m, &s.undos[ply])
+		score, _ := s.negamax(pos, depth-1, ply+1, -beta, -alpha, eval)
+		score = -score
+		pos.UnmakeMove(&s.undos[ply])
+		if score > bestScore {
+			best, bestScore = m, score
+		}
+		if score > alpha {
+			alpha = score
+		}
+		if alpha >= beta {
+			break
+		}
+	}
+	return bestScore, best
+}
+
+// validMoves generates the position's valid moves into the buffer
+// for the ply.
+func (s *Searcher) validMoves(pos *Position, ply int) []*Move {
+	for len(s.moves) <= ply {
+		s.moves = append(s.moves, make([]*Move, 0, 64))
+		s.undos = append(s.undos, Undo{})
+	}
+	s.moves[ply] = engine{}.appendMoves(s.moves[ply][:0], pos, false, nil)
+	return s.moves[ply]
+}
@@ -0,0 +1,52 @@
+package chess
+
+import "testing"
+
+func TestSearcherPerft(t *testing.T) {
+	s := NewSearcher()
+	for _, perf := range perfResults {
+		fen := perf.pos.String()
+		for depth, expected := range perf.nodesPerDepth {
+			if depth > 2 {
+				break
+			}
+			if nodes := s.Perft(perf.pos, depth+1); nodes != expected {
+				t.Fatalf("expected %d nodes at depth %d for %s but got %d", expected, depth+1, fen, nodes)
+			}
+		}
+		if perf.pos.String() != fen {
+			t.Fatalf("expected perft not to modify %s but got %s", fen, perf.pos)
+		}
+	}
+}
+
+func TestSearcherNegamax(t *testing.T) {
+	material := func(pos *Position) int {
+		score := pos.EvaluateTapered()
+		if pos.Turn() == Black {
+			return -score
+		}
+		return score
+	}
+	s := NewSearcher()
+	// back rank mate with Ra8#
+	pos := unsafeFEN("6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1")
+	score, m := s.Negamax(pos, 2, material)
+	if m == nil || m.S1() != A1 || m.S2() != A8 || score != MateScore-1 {
+		t.Fatalf("expected Ra8# with score %d but got %v with %d", MateScore-1, m, score)
+	}
+	// the hanging queen on d5 is captured
+	pos = unsafeFEN("4k3/8/8/3q4/8/8/8/3RK3 w - - 0 1")
+	if _, m := s.Negamax(pos, 2, material); m == nil || m.S2() != D5 {
+		t.Fatalf("expected Rxd5 but got %v", m)
+	}
+}
+
+func BenchmarkSearcherPerft(b *testing.B) {
+	pos := StartingPosition()
+	s := NewSearcher()
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		s.Perft(pos, 3)
+	}
+}
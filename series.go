@@ -0,0 +1,221 @@
+package chess
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PlyData is the annotation data for a single move returned from
+// Game's EvalSeries method.
+type PlyData struct {
+	// Ply is the one based index of the move.
+	Ply int
+	// SAN is the move in algebraic notation.
+	SAN string
+	// HasEval is true if the move's comments include an evaluation.
+	HasEval bool
+	// CP is the evaluation in centipawns from white's perspective.
+	CP int
+	// Mate is the number of moves until mate, negative if black is
+	// mating, or zero if the evaluation isn't a mate score.
+	Mate int
+	// WhiteClock and BlackClock are the most recent remaining clock
+	// times for each color or zero if none has been recorded yet.
+	WhiteClock time.Duration
+	BlackClock time.Duration
+}
+
+var (
+	evalCommentRe  = regexp.MustCompile(`\[%eval\s+(#?[+-]?\d+(?:\.\d+)?)`)
+	clockCommentRe = regexp.MustCompile(`\[%clk\s+(\d+):(\d+):(\d+(?:\.\d+)?)\]`)
+	clockTagRe     = regexp.MustCompile(`^\s*(\d+):(\d+):(\d+(?:\.\d+)?)\s*$`)
+)
+
+// EvalSeries returns the evaluation and clock data for each move
+// parsed from [%eval ...] and [%clk ...] comment commands such as
+// those exported by lichess.  Clock times are carried forward so
+// each entry has the remaining time for both colors.
+func (g *Game) EvalSeries() []PlyData {
+	series := make([]PlyData, 0, len(g.moves))
+	var white, black time.Duration
+	for i, m := range g.moves {
+		pos := g.positions[i]
+		d := PlyData{
+			Ply: i + 1,
+			SAN: AlgebraicNotation{}.Encode(pos, m),
+		}
+		comment := ""
+		if len(g.comments) > i {
+			comment = strings.Join(g.comments[i], " ")
+		}
+		if match := evalCommentRe.FindStringSubmatch(comment); match != nil {
+			d.HasEval = parseEvalComment(match[1], &d)
+		}
+		if clk, ok := parseClockComment(comment); ok {
+			if pos.turn == White {
+				white = clk
+			} else {
+				black = clk
+			}
+		}
+		d.WhiteClock, d.BlackClock = white, black
+		series = append(series, d)
+	}
+	return series
+}
+
+// parseEvalComment sets the centipawn or mate score of d from the
+// value of an eval command and returns true if it is valid.
+func parseEvalComment(s string, d *PlyData) bool {
+	if strings.HasPrefix(s, "#") {
+		mate, err := strconv.Atoi(s[1:])
+		if err != nil {
+			return false
+		}
+		d.Mate = mate
+		return true
+	}
+	pawns, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return false
+	}
+	d.CP = int(math.Round(pawns * 100))
+	return true
+}
+
+// parseClockComment returns the remaining time of a clk command in
+// the comment.
+func parseClockComment(comment string) (time.Duration, bool) {
+	return parseClock(clockCommentRe, comment)
+}
+
+// parseClock returns the h:mm:ss time matched by re in s.
+func parseClock(re *regexp.Regexp, s string) (time.Duration, bool) {
+	match := re.FindStringSubmatch(s)
+	if match == nil {
+		return 0, false
+	}
+	h, _ := strconv.Atoi(match[1])
+	m, _ := strconv.Atoi(match[2])
+	sec, _ := strconv.ParseFloat(match[3], 64)
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(sec*float64(time.Second)), true
+}
+
+// Frame is a single step of a game returned from Game's Frames
+// method.  The root frame has a nil Move.
+type Frame struct {
+	Position *Position
+	Move     *Move
+	SAN      string
+	Comments []string
+	// Data holds the evaluation and clocks parsed from the comments.
+	Data PlyData
+}
+
+// Frames returns the game's initial position followed by a frame
+// for every move with the resulting position, the move in algebraic
+// notation, its comments, and evaluation.  It combines MoveHistory
+// and EvalSeries for building game viewers.
+func (g *Game) Frames() []Frame {
+	series := g.EvalSeries()
+	frames := make([]Frame, 0, len(g.positions))
+	frames = append(frames, Frame{Position: g.positions[0]})
+	for i, m := range g.moves {
+		f := Frame{
+			Position: g.positions[i+1],
+			Move:     m,
+			SAN:      series[i].SAN,
+			Data:     series[i],
+		}
+		if len(g.comments) > i {
+			f.Comments = g.comments[i]
+		}
+		frames = append(frames, f)
+	}
+	return frames
+}
+
+// Clocks returns the remaining time of each color.  The most recent
+// [%clk ...] comment of each color is used, falling back to the
+// WhiteClock and BlackClock tag pairs.  The ok results are false if
+// neither is present.
+func (g *Game) Clocks() (white, black time.Duration, whiteOK, blackOK bool) {
+	white, whiteOK = g.clockTag("WhiteClock")
+	black, blackOK = g.clockTag("BlackClock")
+	for i := range g.moves {
+		if len(g.comments) <= i {
+			break
+		}
+		clk, ok := parseClockComment(strings.Join(g.comments[i], " "))
+		if !ok {
+			continue
+		}
+		if g.positions[i].turn == White {
+			white, whiteOK = clk, true
+		} else {
+			black, blackOK = clk, true
+		}
+	}
+	return white, black, whiteOK, blackOK
+}
+
+// clockTag returns the h:mm:ss time stored in the tag pair.
+func (g *Game) clockTag(key string) (time.Duration, bool) {
+	tp := g.GetTagPair(key)
+	if tp == nil {
+		return 0, false
+	}
+	return parseClock(clockTagRe, tp.Value)
+}
+
+// SetClocks stores the remaining time of each color in the
+// WhiteClock and BlackClock tag pairs.
+func (g *Game) SetClocks(white, black time.Duration) {
+	g.AddTagPair("WhiteClock", formatClock(white))
+	g.AddTagPair("BlackClock", formatClock(black))
+}
+
+// SetTimeControl stores the base time and increment in the
+// TimeControl tag pair in seconds, for example "300+2".
+func (g *Game) SetTimeControl(base, increment time.Duration) {
+	tc := strconv.Itoa(int(base / time.Second))
+	if increment > 0 {
+		tc += "+" + strconv.Itoa(int(increment/time.Second))
+	}
+	g.AddTagPair("TimeControl", tc)
+}
+
+// TimeControl returns the base time and increment of the game's
+// TimeControl tag pair.  The ok result is false if the tag is missing
+// or isn't in the "base+increment" seconds form.
+func (g *Game) TimeControl() (base, increment time.Duration, ok bool) {
+	tp := g.GetTagPair("TimeControl")
+	if tp == nil {
+		return 0, 0, false
+	}
+	parts := strings.SplitN(tp.Value, "+", 2)
+	b, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	inc := 0
+	if len(parts) == 2 {
+		if inc, err = strconv.Atoi(parts[1]); err != nil {
+			return 0, 0, false
+		}
+	}
+	return time.Duration(b) * time.Second, time.Duration(inc) * time.Second, true
+}
+
+// formatClock formats d in the h:mm:ss form used by clk commands.
+func formatClock(d time.Duration) string {
+	d = d.Round(time.Second)
+	h := d / time.Hour
+	m := (d % time.Hour) / time.Minute
+	sec := (d % time.Minute) / time.Second
+	return fmt.Sprintf("%d:%02d:%02d", h, m, sec)
+}
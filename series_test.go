@@ -0,0 +1,81 @@
+package chess
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEvalSeries(t *testing.T) {
+	pgn := `1. e4 { [%eval 0.24] [%clk 0:05:00] } 1... e5 { [%eval 0.32] [%clk 0:04:58] } 2. Qh5 { [%clk 0:04:55] } 2... Ke7 { [%eval #3] } 3. Qxe5# 1-0`
+	g, err := decodePGN(pgn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	series := g.EvalSeries()
+	expected := []PlyData{
+		{Ply: 1, SAN: "e4", HasEval: true, CP: 24, WhiteClock: 5 * time.Minute},
+		{Ply: 2, SAN: "e5", HasEval: true, CP: 32, WhiteClock: 5 * time.Minute, BlackClock: 4*time.Minute + 58*time.Second},
+		{Ply: 3, SAN: "Qh5", WhiteClock: 4*time.Minute + 55*time.Second, BlackClock: 4*time.Minute + 58*time.Second},
+		{Ply: 4, SAN: "Ke7", HasEval: true, Mate: 3, WhiteClock: 4*time.Minute + 55*time.Second, BlackClock: 4*time.Minute + 58*time.Second},
+		{Ply: 5, SAN: "Qxe5#", WhiteClock: 4*time.Minute + 55*time.Second, BlackClock: 4*time.Minute + 58*time.Second},
+	}
+	if len(series) != len(expected) {
+		t.Fatalf("expected %d entries but got %d", len(expected), len(series))
+	}
+	for i := range expected {
+		if series[i] != expected[i] {
+			t.Fatalf("expected entry %d to be %+v but got %+v", i, expected[i], series[i])
+		}
+	}
+}
+
+func TestFrames(t *testing.T) {
+	g, err := decodePGN(`1. e4 { [%eval 0.24] Best by test } 1... e5 2. Nf3 *`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frames := g.Frames()
+	if len(frames) != len(g.Moves())+1 {
+		t.Fatalf("expected %d frames but got %d", len(g.Moves())+1, len(frames))
+	}
+	if frames[0].Move != nil || frames[0].Position != g.Positions()[0] {
+		t.Fatal("expected root frame to have the initial position and a nil move")
+	}
+	f := frames[1]
+	if f.SAN != "e4" || f.Data.CP != 24 || len(f.Comments) != 1 || f.Position != g.Positions()[1] {
+		t.Fatalf("unexpected first move frame %+v", f)
+	}
+	if frames[3].Position != g.Position() {
+		t.Fatal("expected last frame to have the current position")
+	}
+}
+
+func TestClockTags(t *testing.T) {
+	g, err := decodePGN(`[WhiteClock "0:03:00"]
+[BlackClock "0:02:30"]
+
+1. e4 { [%clk 0:02:58] } 1... e5 2. Nf3 *`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	white, black, whiteOK, blackOK := g.Clocks()
+	if !whiteOK || !blackOK || white != 2*time.Minute+58*time.Second || black != 2*time.Minute+30*time.Second {
+		t.Fatalf("expected clocks 2m58s and 2m30s but got %s and %s", white, black)
+	}
+	g.SetTimeControl(3*time.Minute, 2*time.Second)
+	g.SetClocks(white, black)
+	pgn := g.String()
+	for _, tag := range []string{`[TimeControl "180+2"]`, `[WhiteClock "0:02:58"]`, `[BlackClock "0:02:30"]`} {
+		if !strings.Contains(pgn, tag) {
+			t.Fatalf("expected exported pgn to contain %s but got %s", tag, pgn)
+		}
+	}
+	base, inc, ok := g.TimeControl()
+	if !ok || base != 3*time.Minute || inc != 2*time.Second {
+		t.Fatalf("expected time control 3m+2s but got %s+%s", base, inc)
+	}
+	if _, _, whiteOK, blackOK := NewGame().Clocks(); whiteOK || blackOK {
+		t.Fatal("expected a new game to have no clocks")
+	}
+}
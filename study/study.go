@@ -0,0 +1,176 @@
+// Package study converts games to and from the JSON chapter format
+// used by lichess style study tools.
+package study
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	chess "github.com/krunduev/notnil-chess"
+)
+
+// A Chapter is a game represented as a tree of nodes.
+type Chapter struct {
+	Tags   []Tag  `json:"tags,omitempty"`
+	Root   *Node  `json:"root"`
+	Result string `json:"result"`
+}
+
+// A Tag is a PGN tag pair of a chapter.
+type Tag struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// A Node is a position in a chapter's tree.  The root node has no
+// move.  The first child continues the mainline and the remaining
+// children are variations.
+type Node struct {
+	Ply      int      `json:"ply"`
+	FEN      string   `json:"fen"`
+	SAN      string   `json:"san,omitempty"`
+	UCI      string   `json:"uci,omitempty"`
+	Comments []string `json:"comments,omitempty"`
+	NAGs     []int    `json:"glyphs,omitempty"`
+	Children []*Node  `json:"children,omitempty"`
+}
+
+// FromGame returns a chapter with the game's tags, moves, comments,
+// NAGs, and variations.
+func FromGame(g *chess.Game) *Chapter {
+	c := &Chapter{Result: string(g.Outcome())}
+	for _, tp := range g.TagPairs() {
+		c.Tags = append(c.Tags, Tag{Name: tp.Key, Value: tp.Value})
+	}
+	positions := g.Positions()
+	variations := g.Variations()
+	c.Root = &Node{FEN: positions[0].String()}
+	parent := c.Root
+	for i, mh := range g.MoveHistory() {
+		n := newNode(mh.PrePosition, mh.Move, i+1)
+		n.Comments = mh.Comments
+		n.NAGs = mh.NAGs
+		parent.Children = append(parent.Children, n)
+		if len(variations) > i {
+			for _, v := range variations[i] {
+				if vn := decodeVariation(mh.PrePosition, v, i+1); vn != nil {
+					parent.Children = append(parent.Children, vn)
+				}
+			}
+		}
+		parent = n
+	}
+	return c
+}
+
+// ToGame returns the game described by the chapter.  Variations of
+// variations aren't supported by the PGN decoder and are dropped.
+func ToGame(c *Chapter) (*chess.Game, error) {
+	if c.Root == nil {
+		return nil, fmt.Errorf("study: chapter has no root node")
+	}
+	var sb strings.Builder
+	hasFEN := false
+	for _, tag := range c.Tags {
+		hasFEN = hasFEN || strings.EqualFold(tag.Name, "FEN")
+		fmt.Fprintf(&sb, "[%s %q]\n", tag.Name, tag.Value)
+	}
+	if !hasFEN && c.Root.FEN != chess.StartingPosition().String() {
+		fmt.Fprintf(&sb, "[FEN %q]\n", c.Root.FEN)
+	}
+	sb.WriteString("\n")
+	for n := c.Root; len(n.Children) > 0; n = n.Children[0] {
+		writeNode(&sb, n.Children[0])
+		for _, v := range n.Children[1:] {
+			sb.WriteString("( ")
+			for ; v != nil; v = firstChild(v) {
+				writeNode(&sb, v)
+			}
+			sb.WriteString(") ")
+		}
+	}
+	result := c.Result
+	if result == "" {
+		result = string(chess.NoOutcome)
+	}
+	sb.WriteString(result)
+	opt, err := chess.PGN(strings.NewReader(sb.String()))
+	if err != nil {
+		return nil, err
+	}
+	return chess.NewGame(opt), nil
+}
+
+func newNode(pos *chess.Position, m *chess.Move, ply int) *Node {
+	return &Node{
+		Ply: ply,
+		FEN: pos.Update(m).String(),
+		SAN: chess.AlgebraicNotation{}.Encode(pos, m),
+		UCI: chess.UCINotation{}.Encode(pos, m),
+	}
+}
+
+func firstChild(n *Node) *Node {
+	if len(n.Children) == 0 {
+		return nil
+	}
+	return n.Children[0]
+}
+
+// writeNode writes the node's move and annotations as PGN movetext.
+// Move numbers are omitted since the decoder doesn't require them.
+func writeNode(sb *strings.Builder, n *Node) {
+	move := n.SAN
+	if move == "" {
+		move = n.UCI
+	}
+	sb.WriteString(move + " ")
+	for _, nag := range n.NAGs {
+		fmt.Fprintf(sb, "$%d ", nag)
+	}
+	for _, c := range n.Comments {
+		fmt.Fprintf(sb, "{ %s } ", c)
+	}
+}
+
+var variationTokenRe = regexp.MustCompile(`\{([^}]*)\}|\S+`)
+
+// decodeVariation returns the chain of nodes for the variation text
+// played from pos.  Decoding stops at the first token that isn't a
+// move, comment, or NAG.
+func decodeVariation(pos *chess.Position, text string, ply int) *Node {
+	var first, last *Node
+	for _, match := range variationTokenRe.FindAllStringSubmatch(text, -1) {
+		tok := match[0]
+		switch {
+		case strings.HasPrefix(tok, "{"):
+			if last != nil {
+				last.Comments = append(last.Comments, strings.TrimSpace(match[1]))
+			}
+			continue
+		case strings.HasPrefix(tok, "$"):
+			if nag, err := strconv.Atoi(tok[1:]); err == nil && last != nil {
+				last.NAGs = append(last.NAGs, nag)
+			}
+			continue
+		case strings.HasSuffix(tok, "."):
+			continue
+		}
+		m, err := chess.AlgebraicNotation{}.Decode(pos, tok)
+		if err != nil {
+			break
+		}
+		n := newNode(pos, m, ply)
+		if first == nil {
+			first = n
+		} else {
+			last.Children = append(last.Children, n)
+		}
+		last = n
+		pos = pos.Update(m)
+		ply++
+	}
+	return first
+}
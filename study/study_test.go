@@ -0,0 +1,47 @@
+package study_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	chess "github.com/krunduev/notnil-chess"
+	"github.com/krunduev/notnil-chess/study"
+)
+
+func TestRoundTrip(t *testing.T) {
+	pgn := `[Event "Study"]
+
+1. e4 { King's pawn } 1... e5 2. Nf3 $1 (2. f4 exf4 3. Nf3) 2... Nc6 { Developing } *`
+	opt, err := chess.PGN(strings.NewReader(pgn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := chess.NewGame(opt)
+	b, err := json.Marshal(study.FromGame(g))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &study.Chapter{}
+	if err := json.Unmarshal(b, c); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Root.Children) != 1 || len(c.Root.Children[0].Children) != 1 {
+		t.Fatal("expected a single mainline through the first two plies")
+	}
+	alternatives := c.Root.Children[0].Children[0].Children
+	if len(alternatives) != 2 || alternatives[1].SAN != "f4" || alternatives[1].UCI != "f2f4" {
+		t.Fatalf("expected 2. f4 as a variation of 2. Nf3 but got %s", b)
+	}
+	g2, err := study.ToGame(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.String() != g2.String() {
+		t.Fatalf("expected round trip to produce %s but got %s", g, g2)
+	}
+	if !reflect.DeepEqual(g.Variations(), g2.Variations()) {
+		t.Fatalf("expected variations %v but got %v", g.Variations(), g2.Variations())
+	}
+}
@@ -0,0 +1,109 @@
+package chess
+
+// Transform is one of the eight symmetries of the board.
+type Transform uint8
+
+const (
+	// Identity leaves the board unchanged.
+	Identity Transform = iota
+	// Rotate90 rotates the board 90 degrees clockwise.
+	Rotate90
+	// Rotate180 rotates the board 180 degrees.
+	Rotate180
+	// Rotate270 rotates the board 270 degrees clockwise.
+	Rotate270
+	// FlipLeftRight mirrors the board's file values.
+	FlipLeftRight
+	// FlipUpDown mirrors the board's rank values.
+	FlipUpDown
+	// FlipDiagonal mirrors the board over the A1 to H8 diagonal.
+	FlipDiagonal
+	// FlipAntiDiagonal mirrors the board over the A8 to H1 diagonal.
+	FlipAntiDiagonal
+)
+
+// String implements the fmt.Stringer interface
+func (t Transform) String() string {
+	switch t {
+	case Identity:
+		return "Identity"
+	case Rotate90:
+		return "Rotate90"
+	case Rotate180:
+		return "Rotate180"
+	case Rotate270:
+		return "Rotate270"
+	case FlipLeftRight:
+		return "FlipLeftRight"
+	case FlipUpDown:
+		return "FlipUpDown"
+	case FlipDiagonal:
+		return "FlipDiagonal"
+	case FlipAntiDiagonal:
+		return "FlipAntiDiagonal"
+	}
+	return ""
+}
+
+// Square returns the square that sq is moved to by the transform.
+func (t Transform) Square(sq Square) Square {
+	if sq == NoSquare {
+		return NoSquare
+	}
+	f, r := int(sq.File()), int(sq.Rank())
+	switch t {
+	case Rotate90:
+		f, r = r, 7-f
+	case Rotate180:
+		f, r = 7-f, 7-r
+	case Rotate270:
+		f, r = 7-r, f
+	case FlipLeftRight:
+		f = 7 - f
+	case FlipUpDown:
+		r = 7 - r
+	case FlipDiagonal:
+		f, r = r, f
+	case FlipAntiDiagonal:
+		f, r = 7-r, 7-f
+	}
+	return NewSquare(File(f), Rank(r))
+}
+
+func (t Transform) board(b *Board) *Board {
+	m := map[Square]Piece{}
+	for sq, p := range b.SquareMap() {
+		m[t.Square(sq)] = p
+	}
+	return NewBoard(m)
+}
+
+// Canonical returns the canonical representative of the position
+// under the board symmetries that preserve the rules of the game
+// along with the transform that produces it.  Positions with castling
+// rights have no symmetries, positions with pawns may only be mirrored
+// left to right, and pawnless positions allow all eight symmetries.
+// Positions that are equal up to symmetry share the same canonical
+// position.
+func (pos *Position) Canonical() (*Position, Transform) {
+	var transforms []Transform
+	switch {
+	case pos.castleRights != "" && pos.castleRights != "-":
+	case pos.board.bbWhitePawn|pos.board.bbBlackPawn != 0:
+		transforms = []Transform{FlipLeftRight}
+	default:
+		transforms = []Transform{Rotate90, Rotate180, Rotate270,
+			FlipLeftRight, FlipUpDown, FlipDiagonal, FlipAntiDiagonal}
+	}
+	best, bestT := pos.copy(), Identity
+	bestStr := best.board.String()
+	for _, t := range transforms {
+		b := t.board(pos.board)
+		if s := b.String(); s < bestStr {
+			best, bestT, bestStr = pos.copy(), t, s
+			best.board = b
+			best.enPassantSquare = t.Square(pos.enPassantSquare)
+		}
+	}
+	return best, bestT
+}
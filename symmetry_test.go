@@ -0,0 +1,47 @@
+package chess
+
+import "testing"
+
+func TestTransformSquare(t *testing.T) {
+	b := unsafeFEN("8/8/8/8/8/8/1Q6/K6k w - - 0 1").board
+	if b.String() != Identity.board(b).String() {
+		t.Fatal("expected identity to leave the board unchanged")
+	}
+	if Rotate90.Square(A1) != A8 || Rotate180.Square(A1) != H8 || FlipDiagonal.Square(B1) != A2 {
+		t.Fatal("unexpected transformed squares")
+	}
+	if s := b.Rotate180().String(); s != Rotate180.board(b).String() {
+		t.Fatalf("expected Rotate180 to match transform but got %s", s)
+	}
+}
+
+func TestCanonicalMirroredPawnless(t *testing.T) {
+	a := unsafeFEN("8/8/8/8/8/8/1Q6/K6k w - - 0 1")
+	b := unsafeFEN("8/8/8/8/8/8/6Q1/k6K w - - 0 1")
+	ca, _ := a.Canonical()
+	cb, _ := b.Canonical()
+	if ca.String() != cb.String() {
+		t.Fatalf("expected mirrored positions to share canonical form but got %s and %s", ca, cb)
+	}
+	c := unsafeFEN("K6k/1Q6/8/8/8/8/8/8 w - - 0 1")
+	if cc, _ := c.Canonical(); cc.String() != ca.String() {
+		t.Fatalf("expected vertically mirrored pawnless position to share canonical form but got %s", cc)
+	}
+}
+
+func TestCanonicalWithPawns(t *testing.T) {
+	a := unsafeFEN("4k3/8/8/8/8/8/P7/4K3 w - - 0 1")
+	b := unsafeFEN("3k4/8/8/8/8/8/7P/3K4 w - - 0 1")
+	ca, _ := a.Canonical()
+	cb, _ := b.Canonical()
+	if ca.String() != cb.String() {
+		t.Fatalf("expected left right mirror to share canonical form but got %s and %s", ca, cb)
+	}
+	c := unsafeFEN("4k3/p7/8/8/8/8/8/4K3 w - - 0 1")
+	if cc, _ := c.Canonical(); cc.String() == ca.String() {
+		t.Fatal("expected positions with pawns not to be mirrored up and down")
+	}
+	if _, tr := StartingPosition().Canonical(); tr != Identity {
+		t.Fatalf("expected castling rights to prevent symmetry but got %s", tr)
+	}
+}
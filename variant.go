@@ -0,0 +1,117 @@
+package chess
+
+import "strings"
+
+// A Variant is a set of rules that changes how a game is won.
+type Variant uint8
+
+const (
+	// Standard is the game with the standard rules of chess.  Chess960
+	// games are standard games whose castling rooks come from the FEN.
+	Standard Variant = iota
+	// Horde is the game in which White's pawn army plays against
+	// Black's standard pieces.  White has no king and loses when all
+	// of its pieces are captured.  Black wins by checkmate.
+	Horde
+	// RacingKings is the game in which both sides race their kings
+	// to the eighth rank and the first king to reach it wins.  Moves
+	// that give check aren't allowed.  If Black reaches the eighth
+	// rank on the move after White the game is drawn.
+	RacingKings
+)
+
+const (
+	hordeFEN       = "rnbqkbnr/pppppppp/8/1PP2PP1/PPPPPPPP/PPPPPPPP/PPPPPPPP/PPPPPPPP w kq - 0 1"
+	racingKingsFEN = "8/8/8/8/8/8/krbnNBRK/qrbnNBRQ w - - 0 1"
+)
+
+// String implements the fmt.Stringer interface and returns the
+// variant's PGN Variant tag value.
+func (v Variant) String() string {
+	switch v {
+	case Horde:
+		return "Horde"
+	case RacingKings:
+		return "Racing Kings"
+	}
+	return "Standard"
+}
+
+// UseVariant returns a function that sets the rules of the game to
+// the given variant.  A game at the standard starting position starts
+// from the variant's starting position instead.  Options such as FEN
+// that replace the starting position must come before UseVariant and
+// games with moves are left unchanged.  Horde positions without a
+// white king can be read from a PGN with Variant and FEN tags.  The
+// returned function is designed to be used in the NewGame constructor.
+func UseVariant(v Variant) func(*Game) {
+	return func(g *Game) {
+		if len(g.moves) > 0 {
+			return
+		}
+		pos := g.pos.copy()
+		if g.pos.String() == startFEN {
+			switch v {
+			case Horde:
+				pos, _ = decodeFEN(hordeFEN)
+			case RacingKings:
+				pos, _ = decodeFEN(racingKingsFEN)
+			}
+		}
+		pos.variant = v
+		g.pos = pos
+		g.positions = []*Position{pos}
+		g.updatePosition()
+	}
+}
+
+// variantFromTag returns the variant described by a PGN Variant tag
+// value and false if the variant isn't supported.  Chess960 is
+// played with standard rules and castling rooks from the FEN tag.
+func variantFromTag(v string) (Variant, bool) {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "", "standard", "from position", "fromposition",
+		"chess960", "chess 960", "fischerandom", "fischer random":
+		return Standard, true
+	case "horde":
+		return Horde, true
+	case "racing kings", "racingkings":
+		return RacingKings, true
+	}
+	return Standard, false
+}
+
+// variantOutcome returns the outcome of a position that is over by
+// the rules of its variant or NoOutcome.
+func variantOutcome(pos *Position) Outcome {
+	switch pos.variant {
+	case Horde:
+		if pos.board.whiteSqs == 0 {
+			return BlackWon
+		}
+	case RacingKings:
+		white := pos.board.bbWhiteKing&bbRank8 != 0
+		black := pos.board.bbBlackKing&bbRank8 != 0
+		switch {
+		case white && black:
+			return Draw
+		case black:
+			return BlackWon
+		case white && pos.turn == Black && canReachEighthRank(pos):
+			// Black gets one move to draw by reaching the eighth rank
+			return NoOutcome
+		case white:
+			return WhiteWon
+		}
+	}
+	return NoOutcome
+}
+
+// canReachEighthRank returns true if Black's king has a valid move to
+// the eighth rank.
+func canReachEighthRank(pos *Position) bool {
+	moves := engine{}.calcFilteredMoves(pos, true, func(m *Move) bool {
+		return m.s1 == pos.board.blackKingSq && m.s2.Rank() == Rank8
+	})
+	return len(moves) > 0
+}
@@ -0,0 +1,118 @@
+package chess
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVariantPerft(t *testing.T) {
+	tests := []struct {
+		variant       Variant
+		nodesPerDepth []int
+	}{
+		{variant: Horde, nodesPerDepth: []int{8, 128, 1274}},
+		{variant: RacingKings, nodesPerDepth: []int{21, 421, 11264}},
+	}
+	for _, test := range tests {
+		g := NewGame(UseVariant(test.variant))
+		pos := g.Position()
+		countMoves(t, pos, []*Position{pos}, test.nodesPerDepth, len(test.nodesPerDepth))
+	}
+}
+
+func TestRacingKingsWin(t *testing.T) {
+	fen, err := FEN("8/K7/8/8/8/8/8/7k w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGame(fen, UseVariant(RacingKings))
+	if g.Outcome() != NoOutcome {
+		t.Fatalf("expected kings only to be playable but got %s", g.ResultString())
+	}
+	if err := g.MoveStr("Ka8"); err != nil {
+		t.Fatal(err)
+	}
+	if g.Outcome() != WhiteWon || g.Method() != VariantEnd {
+		t.Fatalf("expected white to win by reaching the eighth rank but got %s", g.ResultString())
+	}
+	if !strings.Contains(g.String(), `[Variant "Racing Kings"]`) {
+		t.Fatalf("expected pgn to have a variant tag but got %s", g.String())
+	}
+}
+
+func TestRacingKingsDraw(t *testing.T) {
+	fen, err := FEN("8/K6k/8/8/8/8/8/8 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGame(fen, UseVariant(RacingKings))
+	if err := g.MoveStr("Ka8"); err != nil {
+		t.Fatal(err)
+	}
+	if g.Outcome() != NoOutcome {
+		t.Fatalf("expected black to get a move to reach the eighth rank but got %s", g.ResultString())
+	}
+	if err := g.MoveStr("Kh8"); err != nil {
+		t.Fatal(err)
+	}
+	if g.Outcome() != Draw || g.Method() != VariantEnd {
+		t.Fatalf("expected a draw when both kings reach the eighth rank but got %s", g.ResultString())
+	}
+}
+
+func TestRacingKingsChecks(t *testing.T) {
+	fen, err := FEN("7k/8/8/8/8/8/8/R5K1 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGame(fen, UseVariant(RacingKings))
+	for _, m := range g.ValidMoves() {
+		if m.HasTag(Check) {
+			t.Fatalf("expected moves giving check to be invalid but got %s", m)
+		}
+	}
+	if err := g.MoveStr("Ra8"); err == nil {
+		t.Fatal("expected Ra8 giving check to be invalid")
+	}
+	reason := g.Position().ExplainIllegal(&Move{s1: A1, s2: A8})
+	if reason != "move gives check in racing kings" {
+		t.Fatalf("expected a racing kings check reason but got %q", reason)
+	}
+}
+
+func TestHordeLossByNoPieces(t *testing.T) {
+	pgn := `[Variant "Horde"]
+[FEN "4k3/8/8/8/8/8/4P3/3q4 b - - 0 1"]
+
+1... Qxe2 0-1`
+	g, err := decodePGN(pgn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Outcome() != BlackWon || g.Method() != VariantEnd {
+		t.Fatalf("expected black to win when white has no pieces but got %s", g.ResultString())
+	}
+	if err := g.Position().Validate(); err == nil {
+		t.Fatal("expected the horde position to be over")
+	}
+}
+
+func TestHordePawnsDoublePushFromFirstRank(t *testing.T) {
+	if _, err := variantFEN(Horde, "4k3/8/8/8/8/8/8/P3K3 w - - 0 1"); err == nil {
+		t.Fatal("expected horde position with a white king to be invalid")
+	}
+	fen, err := variantFEN(Horde, "4k3/8/8/8/8/8/8/P7 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGame(fen)
+	if g.Position().Variant() != Horde {
+		t.Fatalf("expected horde position but got %s", g.Position().Variant())
+	}
+	if err := g.MoveStr("a3"); err != nil {
+		t.Fatal(err)
+	}
+	if g.Position().EnPassantSquare() != NoSquare {
+		t.Fatalf("expected no en passant square but got %s", g.Position().EnPassantSquare())
+	}
+}